@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/tmountain/uchess/pkg/mockuci"
+)
+
+// mockuci is a UCI engine answering searches from a script, see the
+// mockuci package for the script format
+func main() {
+	logFile := flag.String("log", "", "file the received commands are appended to")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: mockuci [-log file] <script>\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	script, err := mockuci.ParseFile(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	var log io.Writer
+	if *logFile != "" {
+		f, err := os.OpenFile(*logFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		// Commands are written unbuffered, so a crash loses none
+		log = f
+	}
+	os.Exit(script.Run(os.Stdin, os.Stdout, log))
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	uchess "github.com/tmountain/uchess/pkg"
+)
+
+// analyze runs post-game analysis on every game in a PGN file without
+// starting the UI. The annotated PGN is written to stdout unless an
+// output file is specified, and the per-game summary goes to stderr. The
+// exit code is returned so the engine and output file are closed first
+func analyze(args []string) int {
+	flags := flag.NewFlagSet("analyze", flag.ExitOnError)
+	cfg := flags.String("cfg", "", "config file")
+	out := flags.String("out", "", "annotated PGN output file (default stdout)")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: uchess analyze [-cfg config] [-out file] <pgn>\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 1
+	}
+
+	data, err := ioutil.ReadFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+
+	w := os.Stdout
+	if *out != "" {
+		if w, err = os.Create(*out); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return 1
+		}
+		defer w.Close()
+	}
+
+	config := uchess.LoadConfig(*cfg)
+	_, _, cfgHint := uchess.ImportEngines(config.UCIWhite, config.UCIBlack, config.UCIHint, config.UCIEngines)
+	// Analysis uses the engine at full strength
+	cfgHint.LimitStrength = false
+	eng := uchess.InitEngine(cfgHint, config)
+	defer eng.Close()
+	for _, warning := range eng.Warnings() {
+		fmt.Fprintln(os.Stderr, "Warning: "+warning)
+	}
+
+	for i, pgn := range uchess.SplitPGN(string(data)) {
+		n := i + 1
+		game, err := uchess.DecodePGN(pgn)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Game %v: %v\n", n, err.Error())
+			return 1
+		}
+		progress := func(ply, total int) {
+			fmt.Fprintf(os.Stderr, "\rGame %v: analyzing %v/%v", n, ply+1, total)
+		}
+		analysis, err := uchess.AnalyzeGame(game, eng, cfgHint, progress)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "\nGame %v: %v\n", n, err.Error())
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "\rGame %v: %v\n", n, analysis.Summary())
+		fmt.Fprintln(w, uchess.AnalysisPGN(game, analysis))
+	}
+	return 0
+}
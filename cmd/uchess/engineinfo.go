@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	uchess "github.com/tmountain/uchess/pkg"
+)
+
+// engineInfo starts a configured engine and prints its id and the options
+// it declares, followed by any problems with the configured options
+func engineInfo(args []string) {
+	flags := flag.NewFlagSet("engine-info", flag.ExitOnError)
+	cfg := flags.String("cfg", "", "config file")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: uchess engine-info [-cfg config] <engine name>\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	config := uchess.LoadConfig(*cfg)
+	engine, ok := uchess.FindEngine(flags.Arg(0), config.UCIEngines)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Engine %v not found in the config.\n", flags.Arg(0))
+		os.Exit(1)
+	}
+	info, err := uchess.ProbeEngine(engine)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+
+	fmt.Printf("Name:   %v\n", info.ID["name"])
+	fmt.Printf("Author: %v\n", info.ID["author"])
+	fmt.Printf("Path:   %v\n\n", engine.Path)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Option\tType\tDefault\tRange")
+	for _, o := range info.Options {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", o.Name, o.Type, o.Default, o.Range())
+	}
+	w.Flush()
+
+	warnings := uchess.CheckOptions(engine, config, info)
+	if len(warnings) > 0 {
+		fmt.Printf("\nConfig problems:\n  %v\n", strings.Join(warnings, "\n  "))
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	uchess "github.com/tmountain/uchess/pkg"
+)
+
+// games lists, filters and summarizes the finished games in the game store.
+// A game may be reopened in the UI for review
+func games(args []string) {
+	flags := flag.NewFlagSet("games", flag.ExitOnError)
+	cfg := flags.String("cfg", "", "config file")
+	db := flags.String("db", uchess.GameStorePath(), "PGN file holding the games")
+	var filter uchess.GameFilter
+	flags.StringVar(&filter.Engine, "engine", "", "opponent engine (substring)")
+	flags.StringVar(&filter.Result, "result", "", "result <win|loss|draw|1-0|0-1|1/2-1/2>")
+	flags.StringVar(&filter.Opening, "opening", "", "ECO code or opening name (substring)")
+	flags.StringVar(&filter.Color, "color", "", "color played <white|black>")
+	flags.StringVar(&filter.From, "from", "", "first date (i.e., 2021-03-01)")
+	flags.StringVar(&filter.To, "to", "", "last date (i.e., 2021-03-31)")
+	stats := flags.Bool("stats", false, "show the score against each engine")
+	open := flags.Int("open", 0, "reopen game number n in the UI")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: uchess games [-cfg config] [-db file] [filters] [-stats] [-open n]\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 0 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	records, err := uchess.ReadGameStore(*db)
+	if os.IsNotExist(err) {
+		fmt.Println("No games recorded yet.")
+		return
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+
+	if *open > 0 {
+		if *open > len(records) {
+			fmt.Fprintf(os.Stderr, "Game %v not found.\n", *open)
+			os.Exit(1)
+		}
+		review(*cfg, records[*open-1])
+		return
+	}
+
+	matches := make([]uchess.GameRecord, 0)
+	for _, r := range records {
+		if filter.Match(r) {
+			matches = append(matches, r)
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+	if *stats {
+		fmt.Fprintln(w, "Opponent\tGames\tWins\tDraws\tLosses\tScore")
+		for _, s := range uchess.GameStats(matches) {
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%.1f%%\n", s.Opponent, s.Games, s.Wins, s.Draws, s.Losses, s.Score())
+		}
+		return
+	}
+	fmt.Fprintln(w, "#\tDate\tWhite\tBlack\tResult\tOpening")
+	for _, r := range matches {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\n", r.Index, r.Tags["Date"], r.Tags["White"], r.Tags["Black"],
+			r.Tags["Result"], r.Tags["ECO"]+" "+r.Tags["Opening"])
+	}
+}
+
+// review opens a stored game in the UI. Both sides are played by the
+// human so the engines do not move, and the journal of an unfinished game
+// is left alone
+func review(cfg string, r uchess.GameRecord) {
+	var gs uchess.GameState
+	gs.Reviewing = true
+	gs.Config = uchess.LoadConfig(cfg)
+	gs.Config.WhitePiece = "human"
+	gs.Config.BlackPiece = "human"
+	gs.Config.Chess960 = ""
+	theme := setup(&gs)
+
+	msg := fmt.Sprintf("Reviewing game %v", r.Index)
+	if err := uchess.OpenGame(&gs, r.PGN); err != nil {
+		msg = "\u26A0 Error. Invalid PGN."
+	}
+	run(&gs, theme, msg)
+}
@@ -3,40 +3,85 @@ package main
 import (
 	"os"
 
-	"github.com/gdamore/tcell/v2"
-	"github.com/notnil/chess"
 	uchess "github.com/tmountain/uchess/pkg"
+	"github.com/tmountain/uchess/pkg/tui"
 )
 
 func main() {
+	// Subcommands run without the UI
+	if len(os.Args) > 1 && os.Args[1] == "analyze" {
+		os.Exit(analyze(os.Args[2:]))
+	}
+	// Puzzle trainer
+	if len(os.Args) > 1 && os.Args[1] == "puzzles" {
+		puzzles(os.Args[2:])
+		return
+	}
+	// Game store
+	if len(os.Args) > 1 && os.Args[1] == "games" {
+		games(os.Args[2:])
+		return
+	}
+	// Engine option table
+	if len(os.Args) > 1 && os.Args[1] == "engine-info" {
+		engineInfo(os.Args[2:])
+		return
+	}
+
 	// Game state
 	var gs uchess.GameState
 	// Init via flags
 	gs.Config = uchess.Init()
-	// Import additional themes if available
-	cfgTheme, err := uchess.ImportThemes(gs.Config.ActiveTheme, gs.Config.Themes)
-	// Chess board state
-	gs.Game = chess.NewGame()
+	// Offer to pick up where a crashed or closed session left off
+	journal := uchess.OfferResume(gs.Config)
+	if journal != nil {
+		journal.Apply(&gs.Config)
+	}
+	theme := setup(&gs)
+	// Adaptive mode starts the CPU at the stored level
+	uchess.ApplyLevel(&gs, uchess.LoadLevelStats().Level)
+	msg := ""
+	if journal != nil {
+		if err := uchess.ResumeGame(&gs, journal); err != nil {
+			msg = "\u26A0 Unable to resume the game."
+		} else {
+			msg = "Game resumed."
+		}
+	}
+	run(&gs, theme, msg)
+}
 
+// setup prepares the game and engines for the config and returns the theme
+func setup(gs *uchess.GameState) uchess.Theme {
+	// Import additional themes if available
+	theme, err := uchess.ImportThemes(gs.Config.ActiveTheme, gs.Config.Themes)
 	// A valid theme is required. This should not happen unless someone
 	// changes the config to point to something invalid
 	if err != nil {
 		panic(err)
-	} else {
-		gs.Theme = cfgTheme
 	}
 
 	// Load the FEN if applicable
-	fen, err := chess.FEN(gs.Config.FEN)
+	game, err := uchess.NewGameFEN(gs.Config.FEN, false)
 	if err != nil {
 		panic(err)
 	}
+	// Chess960 replaces the FEN with a numbered start position
+	if gs.Config.Chess960 != "" {
+		n, err := uchess.ParseChess960(gs.Config.Chess960)
+		if err != nil {
+			panic(err)
+		}
+		if game, err = uchess.NewChess960Game(n); err != nil {
+			panic(err)
+		}
+	}
 
 	// This encapsulates the ongoing game state
-	gs.Game = chess.NewGame(fen)
+	uchess.StartGame(gs, game)
 	// Connect to the UCI engines
 	cfgWhite, cfgBlack, cfgHint := uchess.ImportEngines(gs.Config.UCIWhite, gs.Config.UCIBlack, gs.Config.UCIHint, gs.Config.UCIEngines)
-	uciWhite, uciBlack, uciHint := uchess.InitEngines(gs.Config)
+	uciWhite, uciBlack, uciHint := uchess.InitEngines(gs.Config, cfgWhite, cfgBlack, cfgHint)
 	// Store the resulting values in the game state
 	// Configs are stored, to provide future opportunities for commands
 	// to adjust UCI behavior on the fly
@@ -46,116 +91,22 @@ func main() {
 	gs.UCI.CfgWhite = cfgWhite
 	gs.UCI.CfgBlack = cfgBlack
 	gs.UCI.CfgHint = cfgHint
+	gs.UCI.BookWhite = uchess.InitBook(cfgWhite)
+	gs.UCI.BookBlack = uchess.InitBook(cfgBlack)
+	return theme
+}
 
-	// Initialize screen
-	gs.S, err = tcell.NewScreen()
+// run plays the game in the terminal until the user quits, starting with
+// the message when there is one
+func run(gs *uchess.GameState, theme uchess.Theme, msg string) {
+	s, err := tui.NewScreen()
 	if err != nil {
 		panic(err)
 	}
-	if err := gs.S.Init(); err != nil {
-		panic(err)
-	}
-	gs.S.SetStyle(uchess.DefStyle)
-	gs.S.Clear()
-
-	// Input buffer
-	gs.Input = uchess.NewInput()
-	// This handles if the CPU is white (going first)
-	if uchess.IsCPU(gs.Game.Position().Turn(), gs.Config) {
-		// Render before the CPU goes to avoid a blank screen
-		uchess.Render(&gs)
-		uchess.EngMove(gs.Game, gs.UCI, gs.Config)
-	}
-	// Set the initial score
-	gs.Score = uchess.EngScore(gs.Game, gs.UCI, gs.Config)
-	uchess.Render(&gs)
-
-	for {
-		rescore := Interact(&gs)
-		// If the game is still in play, update the score
-		if gs.Game.Outcome() == "*" && rescore {
-			gs.Score = uchess.EngScore(gs.Game, gs.UCI, gs.Config)
-		}
-		uchess.Render(&gs)
-	}
-}
-
-// setChecks indicates if either color is in check
-func setChecks(gs *uchess.GameState) {
-	checkWhite, checkBlack := uchess.InCheck(gs.Game)
-	gs.CheckWhite = checkWhite
-	gs.CheckBlack = checkBlack
-}
-
-// Interact polls user input an dispatches appropriately
-func Interact(gs *uchess.GameState) bool {
-	// This is used to avoid rescoring on every key press
-	rescore := true
-
-	quit := func() {
-		gs.S.Fini()
-		os.Exit(0)
-	}
-
-	// False when playing cpu vs cpu
-	isInteractive := uchess.IsInteractive(gs.Config)
-
-	// Poll event
-	ev := gs.S.PollEvent()
-
-	// Message
-	msg := ""
-
-	// Process event
-	switch ev := ev.(type) {
-	case *tcell.EventKey:
-		switch ev.Key() {
-		// Quit the app
-		case tcell.KeyEscape, tcell.KeyCtrlC:
-			quit()
-		// Redraw
-		case tcell.KeyCtrlL:
-			gs.S.Sync()
-		case tcell.KeyEnter:
-			if isInteractive {
-				// Reset hints when new commands come through
-				gs.Hint = nil
-				// Attempt to process the command
-				msg, gs.Game = uchess.ProcessCmd(gs.Input.Current(), gs)
-				// Set the check state in the event that a check happened
-				setChecks(gs)
-				uchess.DrawMsgLabel(gs.S, msg, gs.Theme)
-				gs.Input.Clear()
-				// Render between moves to show the square that was chosen
-				uchess.Render(gs)
-			}
-
-			// If the game is still in play, CPU takes move (if applicable)
-			if gs.Game.Outcome() == "*" && uchess.IsCPU(gs.Game.Position().Turn(), gs.Config) {
-				uchess.DrawMsgLabel(gs.S, "Thinking...", gs.Theme)
-				// Render between moves to show the square that was chosen
-				uchess.Render(gs)
-				msg = uchess.EngMove(gs.Game, gs.UCI, gs.Config)
-				// Set the check state in the event that a check happened
-				setChecks(gs)
-				uchess.DrawMsgLabel(gs.S, msg, gs.Theme)
-			}
-		// Backspace
-		case tcell.KeyBackspace, tcell.KeyBackspace2:
-			if isInteractive {
-				rescore = false
-				gs.Input.Backspace()
-			}
-		// Append input
-		default:
-			if isInteractive {
-				rescore = false
-				gs.Input.Append(ev.Rune())
-			}
-		}
-
-	case *tcell.EventResize:
-		gs.S.Sync()
+	ui := tui.New(s, uchess.NewController(gs), theme)
+	if msg != "" {
+		ui.Message(msg)
 	}
-	return rescore
+	ui.Run()
+	ui.Close()
 }
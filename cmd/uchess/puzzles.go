@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	uchess "github.com/tmountain/uchess/pkg"
+)
+
+// puzzles runs the tactics trainer on a puzzle file. Both sides are played
+// by the human, the opponent's replies come from the puzzle solutions
+func puzzles(args []string) {
+	flags := flag.NewFlagSet("puzzles", flag.ExitOnError)
+	cfg := flags.String("cfg", "", "config file")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: uchess puzzles [-cfg config] <csv>\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	puzzles, err := uchess.LoadPuzzles(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+
+	var gs uchess.GameState
+	gs.Config = uchess.LoadConfig(*cfg)
+	gs.Config.WhitePiece = "human"
+	gs.Config.BlackPiece = "human"
+	// Puzzles are classical chess
+	gs.Config.Chess960 = ""
+	theme := setup(&gs)
+
+	gs.Puzzle = uchess.NewPuzzleState(flags.Arg(0), puzzles)
+	msg := uchess.StartPuzzle(&gs)
+	run(&gs, theme, msg)
+}
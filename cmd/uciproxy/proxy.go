@@ -2,52 +2,113 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"os/exec"
+	"strconv"
+	"sync"
 )
 
-func logStdout(stdout io.ReadCloser) {
-	// read input from cmd stdout pipe
-	reader := bufio.NewReader(stdout)
-	scanner := bufio.NewScanner(reader)
+// usage is printed for missing or invalid arguments
+const usage = `Usage: uciproxy [-log file] [-json] [-append] <engine> [args...]
+       uciproxy replay [-timing] <session log>
+`
 
+// copyLines forwards the lines of an engine output to w and logs them
+func copyLines(r io.Reader, w io.Writer, log *sessionLog, dir string) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
 	for scanner.Scan() {
-		rsp := scanner.Text()
-		fmt.Println(rsp)
-		log.Printf("> %v\n", rsp)
+		line := scanner.Text()
+		fmt.Fprintln(w, line)
+		log.write(dir, line)
 	}
 }
 
-func main() {
-	if len(os.Args) != 2 {
-		os.Stderr.WriteString("Usage: proxy <uci path>\n")
-		os.Exit(0)
+// exitCode returns the exit code of an engine from the result of Wait.
+// Engines killed by a signal exit with 1
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() > 0 {
+		return exitErr.ExitCode()
 	}
+	return 1
+}
 
-	uciCmd := os.Args[1]
-	f, _ := os.OpenFile("uchess.log", os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
-	log.SetOutput(f)
+// record runs the engine, forwarding stdin, stdout and stderr while logging
+// every line, and exits with the engine's exit code
+func record(args []string) {
+	flags := flag.NewFlagSet("uciproxy", flag.ExitOnError)
+	logFile := flags.String("log", "uchess.log", "session log file")
+	jsonLog := flags.Bool("json", false, "write the log as JSON lines")
+	appendLog := flags.Bool("append", false, "append to the log instead of truncating it")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() < 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	mode := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if *appendLog {
+		mode = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	f, err := os.OpenFile(*logFile, mode, 0666)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
 	defer f.Close()
-	cmd := exec.Command(uciCmd)
-	cstdin, _ := cmd.StdinPipe()
-	cstdout, _ := cmd.StdoutPipe()
-	scanner := bufio.NewScanner(os.Stdin)
+	log := &sessionLog{w: f, json: *jsonLog}
 
+	cmd := exec.Command(flags.Arg(0), flags.Args()[1:]...)
+	stdin, _ := cmd.StdinPipe()
+	stdout, _ := cmd.StdoutPipe()
+	stderr, _ := cmd.StderrPipe()
 	if err := cmd.Start(); err != nil {
-		panic(err)
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
 	}
 
-	go logStdout(cstdout)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		copyLines(stdout, os.Stdout, log, dirOut)
+		wg.Done()
+	}()
+	go func() {
+		copyLines(stderr, os.Stderr, log, dirErr)
+		wg.Done()
+	}()
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			input := scanner.Text()
+			log.write(dirIn, input)
+			fmt.Fprintln(stdin, input)
+		}
+		// The GUI is gone, let the engine see the end of its input
+		stdin.Close()
+	}()
 
-	for scanner.Scan() {
-		input := scanner.Text()
-		cstdin.Write([]byte(fmt.Sprintln(input)))
-		log.Printf("< %v\n", input)
-		f.Sync()
-	}
+	wg.Wait()
+	code := exitCode(cmd.Wait())
+	log.write(dirExit, strconv.Itoa(code))
+	f.Close()
+	os.Exit(code)
+}
 
-	cmd.Wait()
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		replay(os.Args[2:])
+		return
+	}
+	record(os.Args[1:])
 }
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// player answers commands with the engine output of a recorded session
+type player struct {
+	entries []entry
+	next    int  // Entry after the output of the last answered command
+	timing  bool // Reproduce the recorded response times
+}
+
+// find returns the index of the next recorded command equal to cmd. The
+// search starts after the last answered command and wraps around to the
+// start of the session, -1 is returned when cmd was never recorded
+func (p *player) find(cmd string) int {
+	for _, start := range []int{p.next, 0} {
+		for i := start; i < len(p.entries); i++ {
+			if p.entries[i].Dir == dirIn && p.entries[i].Line == cmd {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// answer writes the engine output recorded after entry i up to the next
+// command, where -1 answers with the output before the first command. A
+// recorded exit ends the replay with the recorded exit code
+func (p *player) answer(i int) {
+	start := time.Now()
+	var sent time.Time
+	if i >= 0 {
+		sent = p.entries[i].Time
+	}
+	j := i + 1
+	for ; j < len(p.entries) && p.entries[j].Dir != dirIn; j++ {
+		e := p.entries[j]
+		if p.timing && !sent.IsZero() && !e.Time.IsZero() {
+			time.Sleep(time.Until(start.Add(e.Time.Sub(sent))))
+		}
+		switch e.Dir {
+		case dirOut:
+			fmt.Println(e.Line)
+		case dirErr:
+			fmt.Fprintln(os.Stderr, e.Line)
+		case dirExit:
+			code, _ := strconv.Atoi(e.Line)
+			os.Exit(code)
+		}
+	}
+	p.next = j
+}
+
+// replay acts as a fake engine answering the commands on stdin from a
+// recorded session. Commands which were not recorded are answered for
+// isready and quit, others are reported on stderr and ignored
+func replay(args []string) {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	timing := flags.Bool("timing", false, "reproduce the recorded response times")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	entries, err := readSession(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	p := &player{entries: entries, timing: *timing}
+	p.answer(-1)
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		cmd := strings.TrimSpace(scanner.Text())
+		if i := p.find(cmd); i >= 0 {
+			p.answer(i)
+			continue
+		}
+		switch cmd {
+		case "isready":
+			fmt.Println("readyok")
+		case "quit":
+			os.Exit(0)
+		default:
+			fmt.Fprintf(os.Stderr, "replay: no recorded answer for %q\n", cmd)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// replayEnv makes the test binary run uciproxy with the arguments it holds
+const replayEnv = "UCIPROXY_TEST_ARGS"
+
+func TestMain(m *testing.M) {
+	if args := os.Getenv(replayEnv); args != "" {
+		os.Args = append([]string{"uciproxy"}, strings.Fields(args)...)
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestPlayerFind(t *testing.T) {
+	p := &player{entries: []entry{
+		{Dir: dirIn, Line: "uci"},
+		{Dir: dirOut, Line: "uciok"},
+		{Dir: dirIn, Line: "go depth 8"},
+		{Dir: dirOut, Line: "bestmove e2e4"},
+		{Dir: dirIn, Line: "go depth 8"},
+		{Dir: dirOut, Line: "bestmove e7e5"},
+	}}
+	for _, tc := range []struct {
+		next int
+		cmd  string
+		want int
+	}{
+		{0, "go depth 8", 2},
+		// Repeated commands are answered in the recorded order
+		{4, "go depth 8", 4},
+		// and from the start once the session is used up
+		{6, "go depth 8", 2},
+		{4, "uci", 0},
+		{0, "isready", -1},
+	} {
+		p.next = tc.next
+		if got := p.find(tc.cmd); got != tc.want {
+			t.Errorf("find(%q) after %v = %v, want %v", tc.cmd, tc.next, got, tc.want)
+		}
+	}
+}
+
+// runReplay replays the session for the commands and returns the output,
+// error output and exit code of uciproxy
+func runReplay(t *testing.T, session, commands string) (string, string, int) {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "session.log")
+	if err := ioutil.WriteFile(file, []byte(session), 0644); err != nil {
+		t.Fatal(err)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(exe)
+	cmd.Env = append(os.Environ(), replayEnv+"=replay "+file)
+	cmd.Stdin = strings.NewReader(commands)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	code := exitCode(cmd.Run())
+	return stdout.String(), stderr.String(), code
+}
+
+func TestReplay(t *testing.T) {
+	session := `> Mock by the uchess authors
+< uci
+> id name Mock
+> uciok
+< go depth 8
+! thinking
+> bestmove e2e4
+# 3
+`
+	stdout, stderr, code := runReplay(t, session, "uci\nisready\nstop\ngo depth 8\n")
+	if want := "Mock by the uchess authors\nid name Mock\nuciok\nreadyok\nbestmove e2e4\n"; stdout != want {
+		t.Errorf("replay wrote %q, want %q", stdout, want)
+	}
+	if !strings.Contains(stderr, "thinking") || !strings.Contains(stderr, `no recorded answer for "stop"`) {
+		t.Errorf("replay wrote %q to stderr, want the recorded line and the unknown command", stderr)
+	}
+	// The recorded exit code ends the replay
+	if code != 3 {
+		t.Errorf("replay exited with %v, want the recorded 3", code)
+	}
+
+	if _, _, code := runReplay(t, session, "uci\nquit\ngo depth 8\n"); code != 0 {
+		t.Errorf("replay exited with %v after quit, want 0", code)
+	}
+}
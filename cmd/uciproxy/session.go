@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Directions of the lines in a session log
+const (
+	dirIn   = "in"   // Sent to the engine
+	dirOut  = "out"  // Written by the engine to stdout
+	dirErr  = "err"  // Written by the engine to stderr
+	dirExit = "exit" // The engine exited, the line holds the exit code
+)
+
+// timeFormat is the timestamp format of text logs
+const timeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+// dirTags are the direction tags of text logs. Logs written before the
+// tags were introduced only use < and >
+var dirTags = map[string]string{dirIn: "<", dirOut: ">", dirErr: "!", dirExit: "#"}
+
+// entry is a line of a session log
+type entry struct {
+	Time time.Time `json:"time"`
+	Dir  string    `json:"dir"`
+	Line string    `json:"line"`
+}
+
+// sessionLog writes the lines passing through the proxy with a timestamp
+// and direction, as text or as JSON lines
+type sessionLog struct {
+	mu   sync.Mutex
+	w    io.Writer
+	json bool
+}
+
+// write logs a line
+func (l *sessionLog) write(dir, line string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e := entry{time.Now(), dir, line}
+	if l.json {
+		data, _ := json.Marshal(e)
+		fmt.Fprintf(l.w, "%s\n", data)
+		return
+	}
+	fmt.Fprintf(l.w, "%v %v %v\n", e.Time.Format(timeFormat), dirTags[dir], line)
+}
+
+// readSession reads a session log in either format
+func readSession(file string) ([]entry, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries := make([]entry, 0)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		e, err := parseEntry(line)
+		if err != nil {
+			return nil, fmt.Errorf("%v:%v: %v", file, n, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// parseEntry parses a line of a session log. Text lines hold timestamp
+// fields followed by the direction tag and the line
+func parseEntry(line string) (entry, error) {
+	var e entry
+	if strings.HasPrefix(strings.TrimSpace(line), "{") {
+		err := json.Unmarshal([]byte(line), &e)
+		return e, err
+	}
+	rest := line
+	for rest != "" {
+		field := rest
+		if i := strings.IndexByte(rest, ' '); i >= 0 {
+			field, rest = rest[:i], rest[i+1:]
+		} else {
+			rest = ""
+		}
+		for dir, tag := range dirTags {
+			if field == tag {
+				e.Dir, e.Line = dir, rest
+				return e, nil
+			}
+		}
+		// The timestamp is optional, logs of other tools have none
+		if t, err := time.Parse(timeFormat, field); err == nil {
+			e.Time = t
+		}
+	}
+	return e, fmt.Errorf("no direction tag in %q", line)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseEntry(t *testing.T) {
+	stamp := time.Date(2021, 3, 1, 12, 0, 0, 123456000, time.UTC)
+	for _, tc := range []struct {
+		line string
+		want entry
+	}{
+		{"2021-03-01T12:00:00.123456Z < uci", entry{stamp, dirIn, "uci"}},
+		{"2021-03-01T12:00:00.123456Z > id name A > B", entry{stamp, dirOut, "id name A > B"}},
+		{"2021-03-01T12:00:00.123456Z ! warning", entry{stamp, dirErr, "warning"}},
+		{"2021-03-01T12:00:00.123456Z # 3", entry{stamp, dirExit, "3"}},
+		{"2021-03-01T12:00:00.123456Z >", entry{stamp, dirOut, ""}},
+		// Logs of other tools have no timestamp
+		{"> readyok", entry{time.Time{}, dirOut, "readyok"}},
+		// Logs written before the direction tags were introduced
+		{"2021/03/01 12:00:00 < position startpos", entry{time.Time{}, dirIn, "position startpos"}},
+		{"2021/03/01 12:00:00 > bestmove e2e4", entry{time.Time{}, dirOut, "bestmove e2e4"}},
+		{`{"time":"2021-03-01T12:00:00.123456Z","dir":"out","line":"uciok"}`, entry{stamp, dirOut, "uciok"}},
+	} {
+		got, err := parseEntry(tc.line)
+		if err != nil || !got.Time.Equal(tc.want.Time) || got.Dir != tc.want.Dir || got.Line != tc.want.Line {
+			t.Errorf("parseEntry(%q) = %+v, %v, want %+v", tc.line, got, err, tc.want)
+		}
+	}
+	for _, line := range []string{"2021/03/01 12:00:00 uci", `{"dir":`} {
+		if e, err := parseEntry(line); err == nil {
+			t.Errorf("parseEntry(%q) = %+v, want an error", line, e)
+		}
+	}
+}
+
+func TestSessionLog(t *testing.T) {
+	for _, json := range []bool{false, true} {
+		var buf bytes.Buffer
+		log := &sessionLog{w: &buf, json: json}
+		want := []entry{{Dir: dirIn, Line: "go depth 8"}, {Dir: dirOut, Line: "bestmove e2e4"}, {Dir: dirErr, Line: "< >"}, {Dir: dirExit, Line: "0"}}
+		for _, e := range want {
+			log.write(e.Dir, e.Line)
+		}
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != len(want) {
+			t.Fatalf("json %v: log %q, want %v lines", json, lines, len(want))
+		}
+		for i, line := range lines {
+			e, err := parseEntry(line)
+			if err != nil || e.Time.IsZero() || e.Dir != want[i].Dir || e.Line != want[i].Line {
+				t.Errorf("json %v: parseEntry(%q) = %+v, %v, want %+v", json, line, e, err, want[i])
+			}
+		}
+	}
+}
+
+func TestReadSession(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "session.log")
+	data := "2021/03/01 12:00:00 < uci\n\n2021/03/01 12:00:00 > uciok\n" +
+		`{"time":"2021-03-01T12:00:00Z","dir":"exit","line":"0"}` + "\n"
+	if err := ioutil.WriteFile(file, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := readSession(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[0].Line != "uci" || entries[1].Dir != dirOut || entries[2].Dir != dirExit {
+		t.Errorf("readSession() = %+v, want uci, uciok and the exit", entries)
+	}
+
+	// Errors name the line
+	if err := ioutil.WriteFile(file, []byte("< uci\n\nuciok\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readSession(file); err == nil || !strings.Contains(err.Error(), "session.log:3:") {
+		t.Errorf("readSession() = %v, want an error for line 3", err)
+	}
+	if _, err := readSession(filepath.Join(dir, "missing.log")); err == nil {
+		t.Error("readSession() succeeded, want an error for a missing file")
+	}
+}
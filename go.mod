@@ -12,3 +12,7 @@ require (
 	gopkg.in/check.v1 v1.0.0-20200902074654-038fdea0a05b // indirect
 	gopkg.in/yaml.v2 v2.2.7 // indirect
 )
+
+// The chess module is patched to play Chess960 castling, see
+// third_party/chess/README.md
+replace github.com/notnil/chess => ./third_party/chess
@@ -0,0 +1,276 @@
+package uchess
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/uci"
+)
+
+const (
+	// mateCP is the centipawn value of delivering mate, mates in n
+	// score mateCP - n so that quicker mates score higher
+	mateCP = 10000
+	// evalCap clamps evaluations when computing centipawn loss so that
+	// lines which are already won or lost do not dominate the average
+	evalCap = 1000
+	// analysisPVPlies is the length of the best-move variations in the PGN
+	analysisPVPlies = 6
+)
+
+// Win percentage drops (in points) which classify a move
+const (
+	inaccuracyDrop = 10.0
+	mistakeDrop    = 20.0
+	blunderDrop    = 30.0
+)
+
+// MoveClass classifies a move by how much it lost relative to the engine's choice
+type MoveClass int
+
+// Move classes from best to worst
+const (
+	ClassBest MoveClass = iota
+	ClassGood
+	ClassInaccuracy
+	ClassMistake
+	ClassBlunder
+)
+
+// String returns the name of the move class
+func (c MoveClass) String() string {
+	return [...]string{"Best", "Good", "Inaccuracy", "Mistake", "Blunder"}[c]
+}
+
+// NAG returns the numeric annotation glyph for the move class or
+// zero when the move does not warrant one
+func (c MoveClass) NAG() int {
+	switch c {
+	case ClassInaccuracy:
+		return 6 // ?!
+	case ClassMistake:
+		return 2 // ?
+	case ClassBlunder:
+		return 4 // ??
+	}
+	return 0
+}
+
+// MoveAnalysis holds the evaluation of a single move
+type MoveAnalysis struct {
+	Move     *chess.Move   // Move played
+	Best     *chess.Move   // Engine's choice in the position before the move
+	PV       []*chess.Move // Engine's best line in the position before the move
+	Eval     int           // Centipawn evaluation after the move from white's perspective
+	CPL      int           // Centipawn loss
+	Accuracy float64       // Move accuracy in percent
+	Class    MoveClass     // Move classification
+}
+
+// GameAnalysis holds the evaluation of every move in a game along with
+// the per-side averages. Index 0 of the per-side values is white
+type GameAnalysis struct {
+	Moves    []MoveAnalysis
+	ACPL     [2]float64
+	Accuracy [2]float64
+}
+
+// scoreCP converts an engine score to centipawns
+func scoreCP(s uci.Score) int {
+	switch {
+	case s.Mate > 0:
+		return mateCP - s.Mate
+	case s.Mate < 0:
+		return -mateCP - s.Mate
+	}
+	return s.CP
+}
+
+// clampCP limits a centipawn value to the evaluation cap
+func clampCP(cp int) int {
+	if cp > evalCap {
+		return evalCap
+	} else if cp < -evalCap {
+		return -evalCap
+	}
+	return cp
+}
+
+// moveAccuracy converts the drop in win percentage caused by a move into
+// an accuracy percentage using the curve popularized by lichess
+func moveAccuracy(drop float64) float64 {
+	acc := 103.1668*math.Exp(-0.04354*drop) - 3.1669
+	return math.Max(0, math.Min(100, acc))
+}
+
+// classifyMove classifies a move by the drop in win percentage it caused
+func classifyMove(drop float64, isBest bool) MoveClass {
+	switch {
+	case isBest:
+		return ClassBest
+	case drop >= blunderDrop:
+		return ClassBlunder
+	case drop >= mistakeDrop:
+		return ClassMistake
+	case drop >= inaccuracyDrop:
+		return ClassInaccuracy
+	}
+	return ClassGood
+}
+
+// evalPosition returns the engine's best line and centipawn evaluation for
+// the side to move. Positions without legal moves are scored directly since
+// engines do not return a best move for them
+func evalPosition(eng Engine, cfg *UCIEngine, pos *chess.Position) (uci.SearchResults, int, error) {
+	switch pos.Status() {
+	case chess.Checkmate:
+		return uci.SearchResults{}, -mateCP, nil
+	case chess.Stalemate:
+		return uci.SearchResults{}, 0, nil
+	}
+
+	cmdGo := uci.CmdGo{Depth: cfg.Depth}
+	cmdGo.MoveTime = cfg.MoveTime * time.Millisecond
+	results, err := eng.Search(pos, cmdGo)
+	if err != nil {
+		return uci.SearchResults{}, 0, err
+	}
+	return results, scoreCP(results.Info.Score), nil
+}
+
+// AnalyzeGame evaluates every position in the game with the engine and
+// classifies each move. The progress callback, when provided, is invoked
+// before each position is evaluated
+func AnalyzeGame(game *chess.Game, eng Engine, cfg *UCIEngine, progress func(ply, total int)) (GameAnalysis, error) {
+	var analysis GameAnalysis
+	var totalCPL, totalAcc [2]float64
+	var count [2]int
+	positions := game.Positions()
+	moves := game.Moves()
+
+	// Evaluate every position once, the evaluation after a move is the
+	// negated evaluation of the next position
+	results := make([]uci.SearchResults, len(positions))
+	evals := make([]int, len(positions))
+	for i, pos := range positions {
+		if progress != nil {
+			progress(i, len(positions))
+		}
+		res, cp, err := evalPosition(eng, cfg, pos)
+		if err != nil {
+			return analysis, err
+		}
+		results[i], evals[i] = res, cp
+	}
+
+	for i, move := range moves {
+		side := 0
+		if positions[i].Turn() == chess.Black {
+			side = 1
+		}
+		best := clampCP(evals[i])
+		played := clampCP(-evals[i+1])
+		cpl := best - played
+		if cpl < 0 {
+			cpl = 0
+		}
+		drop := math.Max(0, (WinProb(best)-WinProb(played))*100)
+		bestMove := results[i].BestMove
+		isBest := bestMove != nil && bestMove.S1() == move.S1() && bestMove.S2() == move.S2() && bestMove.Promo() == move.Promo()
+
+		// Report the evaluation after the move from white's perspective
+		eval := -evals[i+1]
+		if side == 1 {
+			eval = evals[i+1]
+		}
+
+		ma := MoveAnalysis{
+			Move:     move,
+			Best:     bestMove,
+			PV:       results[i].Info.PV,
+			Eval:     eval,
+			CPL:      cpl,
+			Accuracy: moveAccuracy(drop),
+			Class:    classifyMove(drop, isBest),
+		}
+		analysis.Moves = append(analysis.Moves, ma)
+		totalCPL[side] += float64(cpl)
+		totalAcc[side] += ma.Accuracy
+		count[side]++
+	}
+
+	for side := 0; side < 2; side++ {
+		if count[side] > 0 {
+			analysis.ACPL[side] = totalCPL[side] / float64(count[side])
+			analysis.Accuracy[side] = totalAcc[side] / float64(count[side])
+		}
+	}
+	return analysis, nil
+}
+
+// fmtEval formats a centipawn evaluation in the [%eval] comment style,
+// pawns for regular scores and #n for forced mates
+func fmtEval(cp int) string {
+	if cp >= mateCP-500 {
+		return fmt.Sprintf("#%v", mateCP-cp)
+	} else if cp <= -mateCP+500 {
+		return fmt.Sprintf("#-%v", mateCP+cp)
+	}
+	return fmt.Sprintf("%.2f", float64(cp)/100)
+}
+
+// Summary returns a one line summary of the per-side statistics
+func (a GameAnalysis) Summary() string {
+	return fmt.Sprintf("White acpl %.0f acc %.1f%%, Black acpl %.0f acc %.1f%%",
+		a.ACPL[0], a.Accuracy[0], a.ACPL[1], a.Accuracy[1])
+}
+
+// analysisNotes adds the analysis to the PGN notes of a game. Existing
+// comments (i.e., board annotations) are kept
+func analysisNotes(game *chess.Game, a GameAnalysis, notes map[int]pgnNote) map[int]pgnNote {
+	positions := game.Positions()
+	summary := notes[0]
+	summary.Comment = strings.TrimSpace(summary.Comment + " " + a.Summary())
+	notes[0] = summary
+
+	for i, ma := range a.Moves {
+		ply := i + 1
+		note := notes[ply]
+		comment := make([]string, 0)
+		if note.Comment != "" {
+			comment = append(comment, note.Comment)
+		}
+		// Mate has been delivered, there is nothing left to evaluate
+		if ma.Eval != mateCP && ma.Eval != -mateCP {
+			comment = append(comment, fmt.Sprintf("[%%eval %v]", fmtEval(ma.Eval)))
+		}
+		if nag := ma.Class.NAG(); nag != 0 {
+			note.NAGs = append(note.NAGs, nag)
+			if ma.Best != nil {
+				if best := findMove(positions[i], ma.Best); best != nil {
+					san := chess.AlgebraicNotation{}.Encode(positions[i], best)
+					comment = append(comment, fmt.Sprintf("%v. %v was best.", ma.Class, san))
+				}
+			}
+			pv := ma.PV
+			if len(pv) > analysisPVPlies {
+				pv = pv[:analysisPVPlies]
+			}
+			note.Variation = pv
+		}
+		note.Comment = strings.Join(comment, " ")
+		notes[ply] = note
+	}
+	return notes
+}
+
+// AnalysisPGN encodes a game along with its analysis as PGN. The opening
+// tags are added to a copy, leaving the game as it was
+func AnalysisPGN(game *chess.Game, a GameAnalysis) string {
+	game = cloneGame(game)
+	addOpeningTags(game)
+	return encodePGN(game, analysisNotes(game, a, make(map[int]pgnNote)))
+}
@@ -0,0 +1,171 @@
+package uchess
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+// Annotation colors use the single letter codes of the
+// [%csl] and [%cal] PGN comment extensions
+const (
+	MarkRed    = "R"
+	MarkGreen  = "G"
+	MarkBlue   = "B"
+	MarkYellow = "Y"
+)
+
+// pvArrowPlies is the number of hint PV plies drawn as arrows
+const pvArrowPlies = 2
+
+// Annotation marks a square or draws an arrow between two squares.
+// Square marks have matching From and To squares
+type Annotation struct {
+	Color string
+	From  chess.Square
+	To    chess.Square
+}
+
+// IsArrow returns a bool indicating whether the annotation is an arrow
+func (a Annotation) IsArrow() bool {
+	return a.From != a.To
+}
+
+// markColor converts a color name (red, green, blue, yellow or the
+// first letter of each) to its annotation color code
+func markColor(name string) (string, error) {
+	switch strings.ToLower(name) {
+	case "r", "red":
+		return MarkRed, nil
+	case "g", "green":
+		return MarkGreen, nil
+	case "b", "blue":
+		return MarkBlue, nil
+	case "y", "yellow":
+		return MarkYellow, nil
+	}
+	return "", errors.New("annotate: unknown color")
+}
+
+// parseSquare converts a square in algebraic notation (i.e., e4) to a chess square
+func parseSquare(s string) (chess.Square, error) {
+	s = strings.ToLower(s)
+	if len(s) != 2 || s[0] < 'a' || s[0] > 'h' || s[1] < '1' || s[1] > '8' {
+		return chess.NoSquare, fmt.Errorf("annotate: invalid square %v", s)
+	}
+	return getSquare(chess.File(s[0]-'a'), chess.Rank(s[1]-'1')), nil
+}
+
+// toggleAnnotation adds an annotation to the list or removes it when the same
+// annotation is already present. Marking a square or arrow in a new color
+// replaces the old color
+func toggleAnnotation(notes []Annotation, a Annotation) []Annotation {
+	result := make([]Annotation, 0, len(notes)+1)
+	found := false
+	for _, n := range notes {
+		if n.From == a.From && n.To == a.To {
+			found = n.Color == a.Color
+			continue
+		}
+		result = append(result, n)
+	}
+	if !found {
+		result = append(result, a)
+	}
+	return result
+}
+
+// pruneAnnotations drops annotations attached to plies past the end of the game
+func pruneAnnotations(notes map[int][]Annotation, plies int) {
+	for ply := range notes {
+		if ply > plies {
+			delete(notes, ply)
+		}
+	}
+}
+
+// pvAnnotations converts the leading moves of a principal variation into
+// arrows, green for the side to move and red for the expected reply
+func pvAnnotations(pv []*chess.Move) []Annotation {
+	notes := make([]Annotation, 0, pvArrowPlies)
+	for i, move := range pv {
+		if i >= pvArrowPlies {
+			break
+		}
+		color := MarkGreen
+		if i%2 == 1 {
+			color = MarkRed
+		}
+		notes = append(notes, Annotation{color, move.S1(), move.S2()})
+	}
+	return notes
+}
+
+// BoardAnnotations returns the annotations for the current position
+// along with any arrows derived from the hint PV
+func BoardAnnotations(gs *GameState) []Annotation {
+	notes := make([]Annotation, 0)
+	notes = append(notes, gs.Annotations[len(gs.Game.Moves())]...)
+	return append(notes, pvAnnotations(gs.HintPV)...)
+}
+
+// annotationComment encodes annotations using the [%csl] and [%cal]
+// PGN comment extensions, i.e., [%csl Ge4,Rd5][%cal Gg1f3]
+func annotationComment(notes []Annotation) string {
+	var squares, arrows []string
+	for _, n := range notes {
+		if n.IsArrow() {
+			arrows = append(arrows, n.Color+n.From.String()+n.To.String())
+		} else {
+			squares = append(squares, n.Color+n.From.String())
+		}
+	}
+	comment := ""
+	if len(squares) > 0 {
+		comment += fmt.Sprintf("[%%csl %v]", strings.Join(squares, ","))
+	}
+	if len(arrows) > 0 {
+		comment += fmt.Sprintf("[%%cal %v]", strings.Join(arrows, ","))
+	}
+	return comment
+}
+
+var annotationRegex = regexp.MustCompile(`\[%(csl|cal)\s+([^\]]*)\]`)
+
+// parseAnnotations extracts [%csl] and [%cal] annotations from a PGN comment
+// Malformed entries are skipped
+func parseAnnotations(comment string) []Annotation {
+	notes := make([]Annotation, 0)
+	for _, match := range annotationRegex.FindAllStringSubmatch(comment, -1) {
+		for _, entry := range strings.Split(match[2], ",") {
+			entry = strings.TrimSpace(entry)
+			if len(entry) != 3 && len(entry) != 5 {
+				continue
+			}
+			color, err := markColor(entry[:1])
+			if err != nil {
+				continue
+			}
+			from, err := parseSquare(entry[1:3])
+			if err != nil {
+				continue
+			}
+			to := from
+			if match[1] == "cal" && len(entry) == 5 {
+				if to, err = parseSquare(entry[3:5]); err != nil {
+					continue
+				}
+			}
+			notes = append(notes, Annotation{color, from, to})
+		}
+	}
+	return notes
+}
+
+// stripAnnotations removes [%csl] and [%cal] commands from a PGN comment
+func stripAnnotations(comment string) string {
+	return strings.TrimSpace(annotationRegex.ReplaceAllString(comment, ""))
+}
@@ -0,0 +1,100 @@
+package uchess
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+const (
+	// defaultBlunderThreshold is the eval drop in centipawns that triggers
+	// the blunder check when the config does not specify one
+	defaultBlunderThreshold = 200
+	// blunderDepth is the search depth of the quick blunder check
+	blunderDepth = 10
+)
+
+// blunderThreshold returns the configured blunder threshold in centipawns
+func blunderThreshold(config Config) int {
+	if config.BlunderThreshold <= 0 {
+		return defaultBlunderThreshold
+	}
+	return config.BlunderThreshold
+}
+
+// quickEval returns the hint engine's evaluation of pos for the side to move
+// along with its best move using a shallow search
+func quickEval(gs *GameState, pos *chess.Position) (int, *chess.Move, error) {
+	cfg := UCIEngine{Depth: blunderDepth}
+	results, cp, err := evalPosition(gs.UCI.UciHint, &cfg, pos)
+	if err != nil {
+		return 0, nil, err
+	}
+	return cp, results.BestMove, nil
+}
+
+// blunderCheck evaluates the position before and after a human move. When
+// the eval drops past the threshold the move is held back for confirmation
+// and a warning including the refutation is returned
+func blunderCheck(gs *GameState, move *chess.Move) (string, bool) {
+	pos := gs.Game.Position()
+	before, _, err := quickEval(gs, pos)
+	if err != nil {
+		return "", false
+	}
+	next := pos.Update(move)
+	after, refutation, err := quickEval(gs, next)
+	if err != nil {
+		return "", false
+	}
+
+	drop := clampCP(before) - clampCP(-after)
+	if drop < blunderThreshold(gs.Config) {
+		return "", false
+	}
+
+	gs.PendingMove = move
+	gs.BlunderChecks++
+	san := chess.AlgebraicNotation{}.Encode(pos, move)
+	reply := "?"
+	if refutation != nil {
+		if m := findMove(next, refutation); m != nil {
+			reply = chess.AlgebraicNotation{}.Encode(next, m)
+		}
+	}
+	return fmt.Sprintf("\u26A0 Blunder check #%v: %v drops %.2f, %v refutes. Play it? [y/n]",
+		gs.BlunderChecks, san, float64(drop)/100, reply), true
+}
+
+// confirmMove resolves a move held back by the blunder check. Answering yes
+// plays the move and anything else takes it back
+func confirmMove(gs *GameState, answer string) string {
+	move := gs.PendingMove
+	gs.PendingMove = nil
+
+	switch strings.ToLower(answer) {
+	case "y", "yes":
+		if err := gs.Game.Move(move); err != nil {
+			return "\u26A0 Illegal. Try again."
+		}
+		return strings.Repeat(" ", 80)
+	}
+	return "Move taken back." + strings.Repeat(" ", 80)
+}
+
+// toggleBlunderCheck turns the blunder check on or off
+func toggleBlunderCheck(gs *GameState, args []string) string {
+	if len(args) == 1 && (args[0] == "on" || args[0] == "off") {
+		gs.Config.BlunderCheck = args[0] == "on"
+	} else if len(args) == 0 {
+		gs.Config.BlunderCheck = !gs.Config.BlunderCheck
+	} else {
+		return "\u26A0 Usage: blundercheck [on|off]"
+	}
+
+	if gs.Config.BlunderCheck {
+		return fmt.Sprintf("Blunder check on (%v cp, fired %v times this game)", blunderThreshold(gs.Config), gs.BlunderChecks)
+	}
+	return "Blunder check off" + strings.Repeat(" ", 80)
+}
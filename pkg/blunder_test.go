@@ -0,0 +1,137 @@
+package uchess
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+// blunderScript evaluates the start position and the replies to e4 and f3
+// for the hint engine of the blunder check
+const blunderScript = `
+position ` + startKey + `
+info depth 10 score cp 30 pv e2e4
+bestmove e2e4
+
+position ` + e4Key + `
+info depth 10 score cp -25 pv e7e5
+bestmove e7e5
+
+position rnbqkbnr/pppppppp/8/8/8/5P2/PPPPP1PP/RNBQKBNR b KQkq
+info depth 10 score cp 250 pv e7e5
+bestmove e7e5
+
+position rnbqkbnr/pppppppp/8/8/6P1/8/PPPPPP1P/RNBQKBNR b KQkq
+info depth 10 score mate 4 pv e7e5
+bestmove e7e5
+`
+
+func TestBlunderThreshold(t *testing.T) {
+	for _, tc := range []struct {
+		threshold int
+		want      int
+	}{
+		{0, defaultBlunderThreshold},
+		{-50, defaultBlunderThreshold},
+		{150, 150},
+	} {
+		if got := blunderThreshold(Config{BlunderThreshold: tc.threshold}); got != tc.want {
+			t.Errorf("blunderThreshold(%v) = %v, want %v", tc.threshold, got, tc.want)
+		}
+	}
+}
+
+func TestClassifyMove(t *testing.T) {
+	for _, tc := range []struct {
+		drop   float64
+		isBest bool
+		want   MoveClass
+	}{
+		{0, true, ClassBest},
+		// The engine's choice is best whatever the drop
+		{45, true, ClassBest},
+		{0, false, ClassGood},
+		{inaccuracyDrop - 0.1, false, ClassGood},
+		{inaccuracyDrop, false, ClassInaccuracy},
+		{mistakeDrop - 0.1, false, ClassInaccuracy},
+		{mistakeDrop, false, ClassMistake},
+		{blunderDrop, false, ClassBlunder},
+		{100, false, ClassBlunder},
+	} {
+		if got := classifyMove(tc.drop, tc.isBest); got != tc.want {
+			t.Errorf("classifyMove(%v, %v) = %v, want %v", tc.drop, tc.isBest, got, tc.want)
+		}
+	}
+}
+
+func TestBlunderCheck(t *testing.T) {
+	for _, tc := range []struct {
+		move      string
+		threshold int
+		want      string // Start of the warning, empty when the move is played
+	}{
+		{"e2e4", 0, ""},
+		{"f2f3", 0, "\u26A0 Blunder check #1: f3 drops 2.80, e5 refutes."},
+		{"f2f3", 300, ""},
+		// Mate scores are capped before the drop is taken
+		{"g2g4", 0, "\u26A0 Blunder check #1: g4 drops 10.30, e5 refutes."},
+	} {
+		cfg := mockEngine(t, "mock", blunderScript)
+		config := testConfig("human", "cpu")
+		config.BlunderThreshold = tc.threshold
+		gs := newTestState(t, config, cfg, cfg, cfg)
+		move, err := chess.UCINotation{}.Decode(gs.Game.Position(), tc.move)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg, held := blunderCheck(gs, move)
+		if held != (tc.want != "") || !strings.HasPrefix(msg, tc.want) {
+			t.Errorf("%v at %v cp: blunderCheck() = %q, %v, want %q", tc.move, tc.threshold, msg, held, tc.want)
+		}
+		if held && (gs.PendingMove != move || gs.BlunderChecks != 1) {
+			t.Errorf("%v: pending move %v after %v checks, want the move held back", tc.move, gs.PendingMove, gs.BlunderChecks)
+		}
+	}
+}
+
+func TestConfirmMove(t *testing.T) {
+	for _, tc := range []struct {
+		answer string
+		moves  int
+	}{
+		{"y", 1},
+		{"YES", 1},
+		{"n", 0},
+		{"", 0},
+	} {
+		gs := &GameState{Game: chess.NewGame()}
+		gs.PendingMove = gs.Game.ValidMoves()[0]
+		confirmMove(gs, tc.answer)
+		if n := len(gs.Game.Moves()); n != tc.moves || gs.PendingMove != nil {
+			t.Errorf("confirmMove(%q) left %v moves and pending %v, want %v moves", tc.answer, n, gs.PendingMove, tc.moves)
+		}
+	}
+}
+
+func TestToggleBlunderCheck(t *testing.T) {
+	for _, tc := range []struct {
+		on   bool
+		args []string
+		want bool
+		msg  string
+	}{
+		{false, nil, true, "Blunder check on (200 cp"},
+		{true, nil, false, "Blunder check off"},
+		{false, []string{"on"}, true, "Blunder check on"},
+		{true, []string{"on"}, true, "Blunder check on"},
+		{true, []string{"off"}, false, "Blunder check off"},
+		{true, []string{"maybe"}, true, "\u26A0 Usage"},
+	} {
+		gs := &GameState{Config: Config{BlunderCheck: tc.on}}
+		msg := toggleBlunderCheck(gs, tc.args)
+		if gs.Config.BlunderCheck != tc.want || !strings.HasPrefix(msg, tc.msg) {
+			t.Errorf("toggleBlunderCheck(%v) from %v = %q, %v, want %q, %v", tc.args, tc.on, msg, gs.Config.BlunderCheck, tc.msg, tc.want)
+		}
+	}
+}
@@ -0,0 +1,461 @@
+package uchess
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/uci"
+)
+
+// featureTimeout is how long a CECP engine may take to send its features.
+// Engines which announce done=0 get engineTimeout instead
+const featureTimeout = 2 * time.Second
+
+// cecpDriver runs an engine speaking the XBoard/CECP protocol. Positions
+// are set up with setboard in force mode, so the engine only thinks when
+// uchess asks for a move. Features with a UCI counterpart are presented
+// as UCI options, i.e., memory as Hash, cores as Threads, egtpath syzygy as
+// SyzygyPath and the fischerandom variant as UCI_Chess960
+type cecpDriver struct {
+	engineBase
+	features map[string]string // Features sent in the handshake
+	chess960 bool              // Games are played as fischerandom
+	pings    int               // Number of the last ping
+}
+
+// newCECPDriver returns a CECP driver for the config
+func newCECPDriver(cfg *UCIEngine, config Config) *cecpDriver {
+	e := &cecpDriver{}
+	e.proto, e.cfg, e.config = e, cfg, config
+	return e
+}
+
+// handshake starts the engine process and reads the features it sends in
+// reply to protover 2. Engines have to support setboard
+func (e *cecpDriver) handshake() error {
+	proc, err := startProcess(e.cfg)
+	if err != nil {
+		return err
+	}
+	e.proc = proc
+	e.id = make(map[string]string)
+	e.options = nil
+	e.features = make(map[string]string)
+	e.chess960 = false
+	if err := e.proc.send("xboard"); err != nil {
+		return err
+	}
+	if err := e.proc.send("protover 2"); err != nil {
+		return err
+	}
+
+	pending := false
+	read := func(line string) bool {
+		if !strings.HasPrefix(line, "feature ") {
+			return false
+		}
+		done := false
+		for _, f := range parseFeatures(line) {
+			switch f.Name {
+			case "option":
+				if o, err := parseCECPOption(f.Value); err == nil {
+					e.options = append(e.options, o)
+				}
+			case "done":
+				pending = f.Value == "0"
+				done = f.Value == "1"
+			default:
+				e.features[f.Name] = f.Value
+			}
+			e.proc.send("accepted " + f.Name)
+		}
+		return done
+	}
+	err = e.proc.readUntil(featureTimeout, read)
+	if err == errEngineTimeout {
+		// Engines without done=0 have sent all features by now
+		err = nil
+		if pending {
+			err = e.proc.readUntil(engineTimeout, read)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if e.features["setboard"] != "1" {
+		return fmt.Errorf("cecp: %v does not support setboard", e.cfg.Name)
+	}
+	if name := e.features["myname"]; name != "" {
+		e.id["name"] = name
+	}
+	e.options = append(cecpOptions(e.features), e.options...)
+	// Thinking output carries the score and principal variation
+	return e.proc.send("post")
+}
+
+// sync waits for the engine to finish processing earlier commands. Engines
+// without the ping feature are not waited for
+func (e *cecpDriver) sync() error {
+	if e.features["ping"] != "1" {
+		return nil
+	}
+	e.pings++
+	pong := fmt.Sprintf("pong %d", e.pings)
+	if err := e.proc.send(fmt.Sprintf("ping %d", e.pings)); err != nil {
+		return err
+	}
+	return e.proc.readUntil(engineTimeout, func(line string) bool {
+		return line == pong
+	})
+}
+
+// sendOption sends an option value as the matching CECP command. Options
+// the engine does not declare have no CECP counterpart, i.e., MultiPV, and
+// are left out
+func (e *cecpDriver) sendOption(name, value string) error {
+	switch strings.ToLower(name) {
+	case "hash":
+		return e.proc.send("memory " + value)
+	case "threads":
+		return e.proc.send("cores " + value)
+	case "syzygypath":
+		return e.proc.send("egtpath syzygy " + value)
+	case "uci_chess960":
+		e.chess960 = value == "true"
+		return e.newGame()
+	}
+	o, ok := findOption(e.options, name)
+	if !ok {
+		return nil
+	}
+	switch o.Type {
+	case "button":
+		return e.press(o.Name)
+	case "check":
+		if strings.EqualFold(value, "true") {
+			value = "1"
+		} else {
+			value = "0"
+		}
+	}
+	return e.proc.send(fmt.Sprintf("option %v=%v", o.Name, value))
+}
+
+// press presses a button option
+func (e *cecpDriver) press(name string) error {
+	return e.proc.send("option " + name)
+}
+
+// newGame resets the engine to a new game in force mode
+func (e *cecpDriver) newGame() error {
+	cmds := []string{"new"}
+	if e.chess960 {
+		cmds = append(cmds, "variant fischerandom")
+	}
+	cmds = append(cmds, "force")
+	for _, cmd := range cmds {
+		if err := e.proc.send(cmd); err != nil {
+			return err
+		}
+	}
+	return e.sync()
+}
+
+// idle does nothing, the engine only thinks during a search
+func (e *cecpDriver) idle() error {
+	return nil
+}
+
+// search sets up the position and lets the engine move. Depth and move time
+// are sent as sd and st, searchmoves has no CECP counterpart
+func (e *cecpDriver) search(pos *chess.Position, cmdGo uci.CmdGo) (uci.SearchResults, error) {
+	var results uci.SearchResults
+	e.proc.drain()
+	e.lines = make(map[int]uci.Info)
+	cmds := []string{"force", "setboard " + pos.String()}
+	if cmdGo.Depth > 0 {
+		cmds = append(cmds, fmt.Sprintf("sd %d", cmdGo.Depth))
+	}
+	if cmdGo.MoveTime > 0 {
+		// st takes whole seconds
+		secs := (cmdGo.MoveTime + time.Second - 1) / time.Second
+		cmds = append(cmds, fmt.Sprintf("st %d", secs))
+	}
+	cmds = append(cmds, "go")
+	for _, cmd := range cmds {
+		if err := e.proc.send(cmd); err != nil {
+			return results, err
+		}
+	}
+
+	var err error
+	readErr := e.proc.readUntil(searchLimit(cmdGo), func(line string) bool {
+		if move, ok := cecpMove(line); ok {
+			results.BestMove, err = parseCECPMove(pos, move)
+			return true
+		}
+		if info, ok := parseThinking(pos, line); ok {
+			e.lines[1] = info
+			results.Info = info
+			return false
+		}
+		if cecpGiveUp(line) {
+			err = fmt.Errorf("cecp: %v answered %q", e.cfg.Name, line)
+			return true
+		}
+		return false
+	})
+	if readErr != nil {
+		return results, readErr
+	}
+	// Keep the engine from thinking on after its move
+	if sendErr := e.proc.send("force"); sendErr != nil {
+		return results, sendErr
+	}
+	return results, err
+}
+
+// Search searches the position with the limits of the go command
+func (e *cecpDriver) Search(pos *chess.Position, cmdGo uci.CmdGo) (uci.SearchResults, error) {
+	var results uci.SearchResults
+	err := e.supervise(func() error {
+		var err error
+		results, err = e.search(pos, cmdGo)
+		return err
+	})
+	return results, err
+}
+
+// Ponder does nothing, CECP engines are kept in force mode between moves
+func (e *cecpDriver) Ponder(pos *chess.Position, reply *chess.Move, cmdGo uci.CmdGo) error {
+	return nil
+}
+
+// Pondering returns false, CECP engines do not ponder
+func (e *cecpDriver) Pondering() bool {
+	return false
+}
+
+// parseFeatures parses the name=value pairs of a feature line. Values may
+// be quoted to include spaces
+func parseFeatures(line string) []Option {
+	features := make([]Option, 0)
+	s := strings.TrimPrefix(line, "feature")
+	for {
+		s = strings.TrimLeft(s, " \t")
+		eq := strings.Index(s, "=")
+		if eq <= 0 {
+			return features
+		}
+		name := s[:eq]
+		s = s[eq+1:]
+		value := ""
+		if strings.HasPrefix(s, `"`) {
+			s = s[1:]
+			if end := strings.Index(s, `"`); end >= 0 {
+				value, s = s[:end], s[end+1:]
+			} else {
+				value, s = s, ""
+			}
+		} else {
+			end := strings.IndexAny(s, " \t")
+			if end < 0 {
+				end = len(s)
+			}
+			value = s[:end]
+			s = s[end:]
+		}
+		features = append(features, Option{name, value})
+	}
+}
+
+// cecpOptionTypes maps the controls of CECP option features to UCI types
+var cecpOptionTypes = map[string]string{
+	"-check":  "check",
+	"-spin":   "spin",
+	"-slider": "spin",
+	"-combo":  "combo",
+	"-button": "button",
+	"-save":   "button",
+	"-reset":  "button",
+	"-string": "string",
+	"-file":   "string",
+	"-path":   "string",
+}
+
+// parseCECPOption parses the value of an option feature, i.e.,
+// Skill -spin 10 0 20 or Style -combo Solid /// *Normal /// Risky
+func parseCECPOption(value string) (EngineOption, error) {
+	var o EngineOption
+	fields := strings.Fields(value)
+	for i, f := range fields {
+		typ, ok := cecpOptionTypes[f]
+		if !ok {
+			continue
+		}
+		o.Name = strings.Join(fields[:i], " ")
+		o.Type = typ
+		args := fields[i+1:]
+		switch typ {
+		case "check":
+			o.Default = "false"
+			if len(args) > 0 && args[0] == "1" {
+				o.Default = "true"
+			}
+		case "spin":
+			if len(args) >= 3 {
+				o.Default, o.Min, o.Max = args[0], args[1], args[2]
+			}
+		case "combo":
+			for _, v := range strings.Split(strings.Join(args, " "), "///") {
+				v = strings.TrimSpace(v)
+				if strings.HasPrefix(v, "*") {
+					v = v[1:]
+					o.Default = v
+				}
+				if v != "" {
+					o.Vars = append(o.Vars, v)
+				}
+			}
+			if len(o.Vars) == 0 {
+				return o, fmt.Errorf("cecp: invalid option %q", value)
+			}
+		case "string":
+			o.Default = strings.Join(args, " ")
+		}
+		if o.Name == "" {
+			break
+		}
+		return o, nil
+	}
+	return o, fmt.Errorf("cecp: invalid option %q", value)
+}
+
+// cecpOptions returns the options a CECP engine supports through its
+// features, named like their UCI counterparts
+func cecpOptions(features map[string]string) []EngineOption {
+	options := make([]EngineOption, 0)
+	if features["memory"] == "1" {
+		options = append(options, EngineOption{"Hash", "spin", "", "1", "1048576", nil})
+	}
+	if features["smp"] == "1" {
+		options = append(options, EngineOption{"Threads", "spin", "", "1", "1024", nil})
+	}
+	if hasFeature(features["egt"], "syzygy") {
+		options = append(options, EngineOption{"SyzygyPath", "string", "", "", "", nil})
+	}
+	if hasFeature(features["variants"], "fischerandom") {
+		options = append(options, EngineOption{"UCI_Chess960", "check", "false", "", "", nil})
+	}
+	return options
+}
+
+// hasFeature returns a bool indicating whether a comma separated feature
+// list holds the value
+func hasFeature(list, value string) bool {
+	for _, v := range strings.Split(list, ",") {
+		if strings.TrimSpace(v) == value {
+			return true
+		}
+	}
+	return false
+}
+
+// cecpMove returns the move of a move line, which older engines write as
+// My move is: e2e4
+func cecpMove(line string) (string, bool) {
+	for _, prefix := range []string{"move ", "My move is: ", "my move is: "} {
+		if strings.HasPrefix(line, prefix) {
+			return strings.TrimSpace(strings.TrimPrefix(line, prefix)), true
+		}
+	}
+	return "", false
+}
+
+// cecpGiveUp returns a bool indicating whether the engine ended the search
+// without a move, i.e., it resigned, claimed a result or rejected the
+// position
+func cecpGiveUp(line string) bool {
+	for _, prefix := range []string{"resign", "1-0", "0-1", "1/2-1/2", "Illegal move", "tellusererror"} {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseCECPMove reads a move in coordinate or standard algebraic notation,
+// as CECP engines may send either
+func parseCECPMove(pos *chess.Position, s string) (*chess.Move, error) {
+	s = strings.TrimRight(s, "+#!?")
+	switch s {
+	case "O-O", "0-0", "O-O-O", "0-0-0":
+		tag := chess.KingSideCastle
+		if len(s) > 3 {
+			tag = chess.QueenSideCastle
+		}
+		for _, m := range pos.ValidMoves() {
+			if m.HasTag(tag) {
+				return m, nil
+			}
+		}
+	}
+	if m, err := (chess.UCINotation{}).Decode(nil, s); err == nil {
+		if valid := findMove(pos, m); valid != nil {
+			return valid, nil
+		}
+	}
+	if m, err := (chess.AlgebraicNotation{}).Decode(pos, s); err == nil {
+		return m, nil
+	}
+	return nil, fmt.Errorf("cecp: invalid move %q", s)
+}
+
+// thinkingLine matches the thinking output of CECP engines: ply, score,
+// time in centiseconds, nodes and the principal variation
+var thinkingLine = regexp.MustCompile(`^\s*(\d+)[.&]?\s+(-?\d+)\s+(\d+)\s+(\d+)\s+(.*)$`)
+
+// cecpMate is the score from which CECP engines report mates, i.e.,
+// 100005 for mate in 5
+const cecpMate = 100000
+
+// parseThinking reads a line of thinking output as UCI info. The principal
+// variation is read up to the first move which does not parse
+func parseThinking(pos *chess.Position, line string) (uci.Info, bool) {
+	var info uci.Info
+	m := thinkingLine.FindStringSubmatch(line)
+	if m == nil {
+		return info, false
+	}
+	info.Depth, _ = strconv.Atoi(m[1])
+	score, _ := strconv.Atoi(m[2])
+	cs, _ := strconv.Atoi(m[3])
+	info.Nodes, _ = strconv.Atoi(m[4])
+	info.Time = time.Duration(cs) * 10 * time.Millisecond
+	switch {
+	case score >= cecpMate:
+		info.Score.Mate = score - cecpMate
+	case score <= -cecpMate:
+		info.Score.Mate = score + cecpMate
+	default:
+		info.Score.CP = score
+	}
+
+	for _, f := range strings.Fields(m[5]) {
+		// Skip move numbers and comments
+		if strings.HasSuffix(f, ".") || strings.HasPrefix(f, "{") || strings.HasPrefix(f, "(") {
+			continue
+		}
+		move, err := parseCECPMove(pos, f)
+		if err != nil {
+			break
+		}
+		info.PV = append(info.PV, move)
+		pos = pos.Update(move)
+	}
+	return info, len(info.PV) > 0
+}
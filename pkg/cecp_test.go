@@ -0,0 +1,260 @@
+package uchess
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/uci"
+)
+
+// cecpFeatures declares the features of the CECP mock engine over two
+// feature lines, with done=0 holding the handshake open
+const cecpFeatures = `feature done=0
+feature myname="Mock CECP" setboard=1 ping=1 memory=1 smp=1 egt="syzygy,gaviota" variants="normal,fischerandom"
+feature option="Style -combo Solid /// *Normal /// Risky"
+feature done=1
+`
+
+// cecpEngine returns an engine config running the script as a mock CECP
+// engine and the file logging the commands it reads
+func cecpEngine(t *testing.T, script string) (*UCIEngine, string) {
+	t.Helper()
+	cfg := mockEngine(t, "mock", script)
+	cfg.Protocol = protocolCECP
+	return cfg, mockLog(t, cfg)
+}
+
+// commands returns the commands read by a mock engine
+func commands(t *testing.T, log string) []string {
+	t.Helper()
+	data, err := ioutil.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+// hasCommands returns a bool indicating whether the commands hold the wanted
+// ones in order
+func hasCommands(cmds []string, want ...string) bool {
+	for _, c := range cmds {
+		if len(want) > 0 && c == want[0] {
+			want = want[1:]
+		}
+	}
+	return len(want) == 0
+}
+
+func TestCECPHandshake(t *testing.T) {
+	cfg, log := cecpEngine(t, cecpFeatures)
+	cfg.Hash = 64
+	cfg.Options = []Option{{"Threads", "2"}, {"Style", "Risky"}}
+	eng, err := StartEngine(cfg, Config{SyzygyPath: "/tb", Chess960: chess960Random})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer eng.Close()
+
+	if name := eng.ID()["name"]; name != "Mock CECP" {
+		t.Errorf("id name %q, want Mock CECP", name)
+	}
+	options, err := eng.Options()
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0)
+	for _, o := range options {
+		names = append(names, o.Name)
+	}
+	if got := strings.Join(names, " "); got != "Hash SyzygyPath Threads UCI_Chess960 Style" &&
+		got != "Hash Threads SyzygyPath UCI_Chess960 Style" {
+		t.Errorf("options %v, want Hash, Threads, SyzygyPath, UCI_Chess960 and Style", got)
+	}
+	// Features are accepted and the options map to their CECP commands
+	cmds := commands(t, log)
+	for _, want := range [][]string{
+		{"xboard", "protover 2", "accepted myname", "accepted option", "post"},
+		{"memory 64"},
+		{"cores 2"},
+		{"egtpath syzygy /tb"},
+		{"option Style=Risky"},
+		{"new", "variant fischerandom", "force", "ping 1"},
+	} {
+		if !hasCommands(cmds, want...) {
+			t.Errorf("engine read %q, want %q", cmds, want)
+		}
+	}
+}
+
+func TestCECPHandshakeWithoutDone(t *testing.T) {
+	cfg, _ := cecpEngine(t, "feature myname=\"Old\" setboard=1\n")
+	start := time.Now()
+	eng, err := StartEngine(cfg, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer eng.Close()
+	// Engines without done have sent their features once they fall silent
+	if elapsed := time.Since(start); elapsed < featureTimeout {
+		t.Errorf("handshake took %v, want the %v feature timeout", elapsed, featureTimeout)
+	}
+	if name := eng.ID()["name"]; name != "Old" {
+		t.Errorf("id name %q, want Old", name)
+	}
+}
+
+func TestCECPHandshakeWithoutSetboard(t *testing.T) {
+	cfg, _ := cecpEngine(t, "feature myname=\"Old\" done=1\n")
+	eng, err := StartEngine(cfg, Config{})
+	if err == nil {
+		eng.Close()
+		t.Fatal("StartEngine() succeeded, want an error for an engine without setboard")
+	}
+	if !strings.Contains(err.Error(), "setboard") {
+		t.Errorf("StartEngine() = %v, want the missing setboard", err)
+	}
+}
+
+func TestCECPSearch(t *testing.T) {
+	cfg, log := cecpEngine(t, cecpFeatures+`
+position `+e4Key+`
+info depth 12 score cp -20 nodes 4500 time 250 pv c7c5 g1f3
+bestmove c7c5
+`)
+	eng, err := StartEngine(cfg, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer eng.Close()
+	game := chess.NewGame()
+	game.MoveStr("e4")
+	res, err := eng.Search(game.Position(), uci.CmdGo{Depth: 12, MoveTime: 1500 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.BestMove == nil || res.BestMove.String() != "c7c5" {
+		t.Errorf("best move %v, want c7c5", res.BestMove)
+	}
+	info := res.Info
+	if info.Depth != 12 || info.Score.CP != -20 || info.Nodes != 4500 || info.Time != 250*time.Millisecond || len(info.PV) != 2 {
+		t.Errorf("info %+v, want depth 12, cp -20, 4500 nodes, 250ms and two PV moves", info)
+	}
+	if lines := eng.Lines(); len(lines) != 1 {
+		t.Errorf("Lines() = %v, want the thinking line", lines)
+	}
+	// The position is set up in force mode with the limits as sd and st
+	want := []string{"force", "setboard " + game.Position().String(), "sd 12", "st 2", "go"}
+	if cmds := commands(t, log); !hasCommands(cmds, want...) {
+		t.Errorf("engine read %q, want %q", cmds, want)
+	}
+}
+
+func TestCECPSearchAlgebraic(t *testing.T) {
+	cfg, _ := cecpEngine(t, cecpFeatures+`
+position *
+send 5 15 30 1000 1. O-O O-O-O 2. Kh1
+send My move is: O-O
+`)
+	eng, err := StartEngine(cfg, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer eng.Close()
+	res, err := eng.Search(position(t, kingE1FEN), uci.CmdGo{Depth: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.BestMove == nil || res.BestMove.String() != "e1g1" || !res.BestMove.HasTag(chess.KingSideCastle) {
+		t.Errorf("best move %v, want castling as e1g1", res.BestMove)
+	}
+	pv := make([]string, 0)
+	for _, m := range res.Info.PV {
+		pv = append(pv, m.String())
+	}
+	if got := strings.Join(pv, " "); got != "e1g1 e8c8 g1h1" {
+		t.Errorf("PV %v, want e1g1 e8c8 g1h1", got)
+	}
+}
+
+func TestCECPSearchResign(t *testing.T) {
+	cfg, _ := cecpEngine(t, cecpFeatures+"position *\nsend resign\n")
+	eng, err := StartEngine(cfg, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer eng.Close()
+	if res, err := eng.Search(chess.StartingPosition(), uci.CmdGo{Depth: 1}); err == nil {
+		t.Errorf("Search() = %v, want an error for the resignation", res.BestMove)
+	}
+}
+
+func TestParseCECPMove(t *testing.T) {
+	promotion := "8/4P1k1/8/8/8/8/8/4K3 w - - 0 1"
+	for _, tc := range []struct {
+		fen  string
+		move string
+		want string // UCI notation, empty for an invalid move
+	}{
+		{startKey, "e2e4", "e2e4"},
+		{startKey, "Nf3", "g1f3"},
+		{startKey, "Nf3!?", "g1f3"},
+		{kingE1FEN, "O-O", "e1g1"},
+		{kingE1FEN, "0-0-0+", "e1c1"},
+		{kingE1FEN, "e1g1", "e1g1"},
+		{promotion, "e7e8q", "e7e8q"},
+		{promotion, "e8=Q+", "e7e8q"},
+		{promotion, "e7e8n", "e7e8n"},
+		{promotion, "e8=N", "e7e8n"},
+		// Chess960 castling takes the rook
+		{kingG1FEN, "O-O", "g1h1"},
+		{kingG1FEN, "O-O-O", "g1b1"},
+		{startKey, "e2e5", ""},
+		{startKey, "Qh5", ""},
+		{promotion, "e8=K", ""},
+	} {
+		fen := tc.fen
+		if fen == startKey {
+			fen += " - 0 1"
+		}
+		m, err := parseCECPMove(position(t, fen), tc.move)
+		switch {
+		case tc.want == "" && err == nil:
+			t.Errorf("%v: parseCECPMove(%q) = %v, want an error", tc.fen, tc.move, m)
+		case tc.want != "" && (err != nil || m.String() != tc.want):
+			t.Errorf("%v: parseCECPMove(%q) = %v, %v, want %v", tc.fen, tc.move, m, err, tc.want)
+		}
+	}
+}
+
+func TestParseThinking(t *testing.T) {
+	pos := chess.StartingPosition()
+	for _, tc := range []struct {
+		line  string
+		score uci.Score
+		pv    int
+	}{
+		{"10 35 120 45000 e2e4 e7e5 Nf3", uci.Score{CP: 35}, 3},
+		{" 9. -12 80 3000 d2d4 {book}", uci.Score{CP: -12}, 1},
+		{"8 100003 5 10 e2e4", uci.Score{Mate: 3}, 1},
+		{"8 -100002 5 10 f2f3", uci.Score{Mate: -2}, 1},
+		// The PV ends at the first move which does not parse
+		{"5 10 0 0 e2e4 e2e4 e7e5", uci.Score{CP: 10}, 1},
+	} {
+		info, ok := parseThinking(pos, tc.line)
+		if !ok || info.Score != tc.score || len(info.PV) != tc.pv {
+			t.Errorf("parseThinking(%q) = %+v, %v, want score %+v and %v PV moves", tc.line, info, ok, tc.score, tc.pv)
+		}
+	}
+	info, _ := parseThinking(pos, "10 35 120 45000 e2e4")
+	if info.Depth != 10 || info.Time != 1200*time.Millisecond || info.Nodes != 45000 {
+		t.Errorf("parseThinking() = %+v, want depth 10, 1.2s and 45000 nodes", info)
+	}
+	for _, line := range []string{"Illegal move: e2e5", "# comment", "10 35 120 45000 zz"} {
+		if info, ok := parseThinking(pos, line); ok {
+			t.Errorf("parseThinking(%q) = %+v, want no thinking line", line, info)
+		}
+	}
+}
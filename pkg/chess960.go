@@ -0,0 +1,209 @@
+package uchess
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+const (
+	// chess960Positions is the number of Chess960 start positions
+	chess960Positions = 960
+	// chess960Random requests a randomly chosen start position
+	chess960Random = "random"
+	// chess960Variant is the value of the PGN Variant tag
+	chess960Variant = "Chess960"
+)
+
+// knightFiles lists the knight placements on the five squares left after
+// the bishops and queen are placed, indexed by the Scharnagl numbering
+var knightFiles = [10][2]int{
+	{0, 1}, {0, 2}, {0, 3}, {0, 4}, {1, 2},
+	{1, 3}, {1, 4}, {2, 3}, {2, 4}, {3, 4},
+}
+
+// Chess960Rank returns the white back rank of start position n (0-959)
+// from the a-file to the h-file using the Scharnagl numbering, where 518
+// is the classical setup
+func Chess960Rank(n int) (string, error) {
+	if n < 0 || n >= chess960Positions {
+		return "", errors.New("chess960: position must be between 0 and 959")
+	}
+	var rank [8]byte
+	// empty returns the file of the idx-th empty square
+	empty := func(idx int) int {
+		for f := range rank {
+			if rank[f] == 0 {
+				if idx == 0 {
+					return f
+				}
+				idx--
+			}
+		}
+		return -1
+	}
+
+	rank[n%4*2+1] = 'B'
+	n /= 4
+	rank[n%4*2] = 'B'
+	n /= 4
+	rank[empty(n%6)] = 'Q'
+	n /= 6
+	// Place the second knight first so the empty squares do not shift
+	rank[empty(knightFiles[n][1])] = 'N'
+	rank[empty(knightFiles[n][0])] = 'N'
+	// The king goes between the rooks
+	rank[empty(0)] = 'R'
+	rank[empty(0)] = 'K'
+	rank[empty(0)] = 'R'
+	return string(rank[:]), nil
+}
+
+// Chess960FEN returns the FEN of start position n. Castling rights are
+// given in X-FEN, where KQkq stand for the rooks on either side of the king
+func Chess960FEN(n int) (string, error) {
+	rank, err := Chess960Rank(n)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%v/pppppppp/8/8/8/8/PPPPPPPP/%v w KQkq - 0 1", strings.ToLower(rank), rank), nil
+}
+
+// ParseChess960 parses a start position number or "random", which picks
+// one of the 960 positions at random
+func ParseChess960(s string) (int, error) {
+	if strings.ToLower(s) == chess960Random {
+		rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+		return rnd.Intn(chess960Positions), nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 || n >= chess960Positions {
+		return 0, errors.New("chess960: position must be between 0 and 959 or random")
+	}
+	return n, nil
+}
+
+// NewChess960Game returns a game from start position n tagged as a
+// Chess960 game so that the PGN records the start position
+func NewChess960Game(n int) (*chess.Game, error) {
+	fen, err := Chess960FEN(n)
+	if err != nil {
+		return nil, err
+	}
+	return NewGameFEN(fen, true)
+}
+
+// IsChess960 returns a bool indicating whether the game is tagged as a
+// Chess960 game
+func IsChess960(game *chess.Game) bool {
+	tag := game.GetTagPair("Variant")
+	return tag != nil && strings.EqualFold(tag.Value, chess960Variant)
+}
+
+// isCastle returns a bool indicating whether the move is a castling move
+func isCastle(m *chess.Move) bool {
+	return m.HasTag(chess.KingSideCastle) || m.HasTag(chess.QueenSideCastle)
+}
+
+// castleRook returns the square the rook of a castling move starts on. The
+// chess module writes castling as the king moving two squares when the
+// king starts on the e-file and the rook in the corner, and as the king
+// taking its rook otherwise
+func castleRook(pos *chess.Position, m *chess.Move) chess.Square {
+	board := pos.Board()
+	king, rook := board.Piece(m.S1()), board.Piece(m.S2())
+	if rook.Type() == chess.Rook && rook.Color() == king.Color() {
+		return m.S2()
+	}
+	sq := chess.Square(int(m.S1()) - int(m.S1().File()))
+	if m.HasTag(chess.KingSideCastle) {
+		sq += chess.Square(chess.FileH)
+	}
+	return sq
+}
+
+// castleMove converts a castling move given as king takes rook, which is
+// how engines in Chess960 mode send castling, into the chess module's
+// castling move. Nil is returned for any other move
+func castleMove(pos *chess.Position, m *chess.Move) *chess.Move {
+	for _, valid := range pos.ValidMoves() {
+		if isCastle(valid) && valid.S1() == m.S1() && castleRook(pos, valid) == m.S2() {
+			return valid
+		}
+	}
+	return nil
+}
+
+// engineCastle returns a castling move written as king takes rook, which
+// is how engines in Chess960 mode expect castling. Other moves are
+// returned as is
+func engineCastle(pos *chess.Position, m *chess.Move) *chess.Move {
+	if !isCastle(m) || castleRook(pos, m) == m.S2() {
+		return m
+	}
+	move, err := chess.UCINotation{}.Decode(pos, m.S1().String()+castleRook(pos, m).String())
+	if err != nil {
+		return m
+	}
+	return move
+}
+
+// castleInput returns the castling move entered as O-O or O-O-O, with
+// zeros as well, or as the king taking its rook, i.e., Kxh1 or g1h1. Nil
+// is returned for any other input
+func castleInput(pos *chess.Position, cmd string) *chess.Move {
+	cmd = strings.Replace(strings.TrimRight(cmd, "+#"), "0", "O", -1)
+	for _, m := range pos.ValidMoves() {
+		if !isCastle(m) {
+			continue
+		}
+		castle := "O-O"
+		if m.HasTag(chess.QueenSideCastle) {
+			castle = "O-O-O"
+		}
+		rook := castleRook(pos, m).String()
+		for _, s := range []string{castle, "K" + rook, "Kx" + rook, m.S1().String() + rook} {
+			if strings.EqualFold(cmd, s) {
+				return m
+			}
+		}
+	}
+	return nil
+}
+
+// startChess960 replaces the current game with a new Chess960 game. The
+// position is picked at random unless a number is given
+func startChess960(gs *GameState, args []string) string {
+	pos := chess960Random
+	if len(args) == 1 {
+		pos = args[0]
+	} else if len(args) > 1 {
+		return "\u26A0 Usage: chess960 [0-959|random]"
+	}
+	n, err := ParseChess960(pos)
+	if err != nil {
+		return "\u26A0 Usage: chess960 [0-959|random]"
+	}
+	game, err := NewChess960Game(n)
+	if err != nil {
+		return "\u26A0 Error. Invalid position."
+	}
+	if gs.Config.Chess960 == "" {
+		gs.Config.Chess960 = pos
+		setChess960(gs.UCI)
+	}
+	StartGame(gs, game)
+	return fmt.Sprintf("Chess960 position %v%v", n, strings.Repeat(" ", 40))
+}
+
+// setChess960 switches the running engines to Chess960 mode
+func setChess960(us UCIState) {
+	for _, eng := range []Engine{us.UciWhite, us.UciBlack, us.UciHint} {
+		eng.SetOption("UCI_Chess960", "true")
+	}
+}
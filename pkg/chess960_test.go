@@ -0,0 +1,183 @@
+package uchess
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+const (
+	// kingG1FEN has the king on g1 castling with the rooks on b1 and h1
+	kingG1FEN = "1r4kr/pppppppp/8/8/8/8/PPPPPPPP/1R4KR w KQkq - 0 1"
+	// kingB1FEN has the king on b1 castling with the rooks on a1 and h1
+	kingB1FEN = "rk5r/pppppppp/8/8/8/8/PPPPPPPP/RK5R w KQkq - 0 1"
+	// kingE1FEN has the king and rooks on their classical squares
+	kingE1FEN = "r3k2r/pppppppp/8/8/8/8/PPPPPPPP/R3K2R w KQkq - 0 1"
+)
+
+// position returns the position of a FEN
+func position(t *testing.T, fen string) *chess.Position {
+	t.Helper()
+	opt, err := chess.FEN(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return chess.NewGame(opt).Position()
+}
+
+func TestChess960FEN(t *testing.T) {
+	for n := 0; n < chess960Positions; n++ {
+		fen, err := Chess960FEN(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Every start position keeps all castling rights
+		if got := position(t, fen).String(); got != fen {
+			t.Errorf("position %v: FEN %v read as %v", n, fen, got)
+		}
+	}
+	if fen, _ := Chess960FEN(518); fen != defaultFEN {
+		t.Errorf("Chess960FEN(518) = %v, want %v", fen, defaultFEN)
+	}
+}
+
+func TestCastleInput(t *testing.T) {
+	for _, tc := range []struct {
+		fen    string
+		inputs []string
+		want   string // Back rank after castling
+	}{
+		{kingG1FEN, []string{"O-O", "0-0+", "Kxh1", "kh1", "g1h1"}, "1R3RK1"},
+		{kingG1FEN, []string{"O-O-O", "0-0-0", "Kxb1", "g1b1"}, "2KR3R"},
+		{kingB1FEN, []string{"O-O", "Kxh1", "b1h1"}, "R4RK1"},
+		{kingB1FEN, []string{"O-O-O", "Kxa1", "b1a1"}, "2KR3R"},
+		{kingE1FEN, []string{"O-O", "0-0", "Kxh1", "e1h1"}, "R4RK1"},
+		{kingE1FEN, []string{"O-O-O", "Kxa1", "e1a1"}, "2KR3R"},
+	} {
+		pos := position(t, tc.fen)
+		for _, input := range tc.inputs {
+			m := castleInput(pos, input)
+			if m == nil {
+				t.Errorf("%v: castleInput(%q) = nil, want castling", tc.fen, input)
+				continue
+			}
+			board := strings.Fields(pos.Update(m).String())[0]
+			if rank := board[strings.LastIndex(board, "/")+1:]; rank != tc.want {
+				t.Errorf("%v: castling with %q left %v, want %v", tc.fen, input, rank, tc.want)
+			}
+			if rights := pos.Update(m).CastleRights().String(); rights != "kq" {
+				t.Errorf("%v: castling with %q left the rights %v, want kq", tc.fen, input, rights)
+			}
+		}
+	}
+}
+
+func TestCastleInputIllegal(t *testing.T) {
+	for _, tc := range []struct {
+		fen   string
+		input string
+	}{
+		// The king would cross an attacked square
+		{"1r4kr/pppppppp/8/8/8/8/PPPrPPPP/1R4KR w KQkq - 0 1", "O-O-O"},
+		// The castling rook shields the king's target square
+		{"k7/8/8/8/8/8/8/qRK4R w KQ - 0 1", "O-O-O"},
+		// A piece stands on the rook's target square
+		{"rk5r/pppppppp/8/8/8/8/PPPPPPPP/RK1N3R w KQkq - 0 1", "O-O-O"},
+		// The rook moved
+		{"1r4kr/pppppppp/8/8/8/8/PPPPPPPP/1R4KR w Qkq - 0 1", "Kxh1"},
+		// Not a castling move
+		{kingG1FEN, "Kf1"},
+	} {
+		if m := castleInput(position(t, tc.fen), tc.input); m != nil {
+			t.Errorf("%v: castleInput(%q) = %v, want nil", tc.fen, tc.input, m)
+		}
+	}
+}
+
+func TestCastleRightsAfterRookMove(t *testing.T) {
+	game, err := NewGameFEN("1r4kr/8/8/8/8/8/8/1R4KR w KQkq - 0 1", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"Rh2", "Rh7", "Rh1", "Rh8"} {
+		if err := game.MoveStr(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The rooks returned to their squares without the rights
+	if rights := game.Position().CastleRights().String(); rights != "Qq" {
+		t.Errorf("castling rights %v, want Qq", rights)
+	}
+}
+
+func TestEngineCastle(t *testing.T) {
+	for _, tc := range []struct {
+		fen    string
+		engine string // Castling as sent by engines
+		module string // Castling as played by the chess module
+	}{
+		{kingE1FEN, "e1h1", "e1g1"},
+		{kingE1FEN, "e1a1", "e1c1"},
+		{kingG1FEN, "g1h1", "g1h1"},
+		{kingB1FEN, "b1a1", "b1a1"},
+	} {
+		pos := position(t, tc.fen)
+		m, err := chess.UCINotation{}.Decode(nil, tc.engine)
+		if err != nil {
+			t.Fatal(err)
+		}
+		move := findMove(pos, m)
+		if move == nil || move.String() != tc.module || !isCastle(move) {
+			t.Errorf("%v: findMove(%v) = %v, want castling as %v", tc.fen, tc.engine, move, tc.module)
+			continue
+		}
+		if got := engineCastle(pos, move).String(); got != tc.engine {
+			t.Errorf("%v: engineCastle(%v) = %v, want %v", tc.fen, move, got, tc.engine)
+		}
+	}
+}
+
+func TestEngMoveChess960Castle(t *testing.T) {
+	cfg := mockEngine(t, "mock", "position 1r4kr/pppppppp/8/8/8/8/PPPPPPPP/1R4KR b KQkq\nbestmove g8h8\n")
+	config := testConfig("human", "cpu")
+	config.Chess960 = chess960Random
+	gs := newTestState(t, config, cfg, cfg, cfg)
+	game, err := NewGameFEN(strings.Replace(kingG1FEN, " w ", " b ", 1), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs.Game = game
+	if msg := EngMove(gs); msg != strings.Repeat(" ", 32) {
+		t.Fatalf("EngMove() = %q, want a cleared label", msg)
+	}
+	if fen := gs.Game.Position().String(); !strings.HasPrefix(fen, "1r3rk1/") {
+		t.Errorf("position %v, want black castled", fen)
+	}
+}
+
+func TestProcessCmdChess960Castle(t *testing.T) {
+	// Human games do not start the engine
+	cfg := &UCIEngine{Name: "none", Path: "uchess-no-engine"}
+	gs := newTestState(t, testConfig("human", "human"), cfg, cfg, cfg)
+	game, err := NewGameFEN(kingB1FEN, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs.Game = game
+	ProcessCmd("Kxa1", gs)
+	if got := playedMove(gs.Game); got != "b1a1" {
+		t.Errorf("played %q, want b1a1", got)
+	}
+	if pgn := gs.Game.String(); !strings.Contains(pgn, "1.O-O-O") {
+		t.Errorf("PGN %v, want 1.O-O-O", pgn)
+	}
+	// Saved games replay the castling
+	saved, err := DecodePGN(gs.Game.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := saved.Position().String(); got != gs.Game.Position().String() {
+		t.Errorf("saved game ends in %v, want %v", got, gs.Game.Position())
+	}
+}
@@ -1,10 +1,11 @@
 package uchess
 
 import (
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"strings"
-	"time"
 
 	"github.com/notnil/chess"
 	"github.com/notnil/chess/image"
@@ -12,8 +13,8 @@ import (
 )
 
 // selectEngine returns the UCI engine and its corresponding config based upon the current turn
-func selectEngine(game *chess.Game, us UCIState) (*uci.Engine, *UCIEngine) {
-	var eng *uci.Engine
+func selectEngine(game *chess.Game, us UCIState) (Engine, *UCIEngine) {
+	var eng Engine
 	var cfg *UCIEngine
 
 	if game.Position().Turn() == chess.White {
@@ -26,52 +27,166 @@ func selectEngine(game *chess.Game, us UCIState) (*uci.Engine, *UCIEngine) {
 	return eng, cfg
 }
 
+// scoreEngine returns the engine scoring the position: the hint engine
+// once it runs, or else the engine of a CPU player, preferring the side to
+// move. Human games do not start an engine just for the score
+func scoreEngine(game *chess.Game, us UCIState, config Config) Engine {
+	turn := game.Position().Turn()
+	switch {
+	case us.UciHint.Started():
+		return us.UciHint
+	case IsCPU(turn, config):
+		eng, _ := selectEngine(game, us)
+		return eng
+	case IsCPU(turn.Other(), config) && turn == chess.White:
+		return us.UciBlack
+	case IsCPU(turn.Other(), config):
+		return us.UciWhite
+	}
+	return nil
+}
+
 // EngScore provides the current board score in centipawns for whomever the current
 // game position identifies as active
-func EngScore(game *chess.Game, us UCIState, config Config) int {
-	eng, _ := selectEngine(game, us)
-	// Update the engine on the game position
-	cmdPos := uci.CmdPosition{Position: game.Position()}
+func EngScore(game *chess.Game, us UCIState, config Config) (int, error) {
+	eng := scoreEngine(game, us, config)
+	if eng == nil {
+		return 0, errors.New("engine: no engine running")
+	}
 	// Do a quick analysis of the current board
 	cmdGo := uci.CmdGo{Depth: 10}
-	// If the command fails for some reason, exit
-	if err := eng.Run(cmdPos, cmdGo); err != nil {
-		panic(err)
+	results, err := eng.Search(game.Position(), cmdGo)
+	if err != nil {
+		return 0, err
 	}
-	info := eng.SearchResults().Info
 	// Return the score in centipawns
-	return info.Score.CP
+	return results.Info.Score.CP, nil
+}
+
+// UpdateScore refreshes the score of the current position. The last score
+// is kept when the engine fails to answer
+func UpdateScore(gs *GameState) {
+	if score, err := EngScore(gs.Game, gs.UCI, gs.Config); err == nil {
+		gs.Score = score
+	}
 }
 
-// EngMove executes a move using the UCI engine
-func EngMove(game *chess.Game, us UCIState, config Config) string {
-	eng, engCfg := selectEngine(game, us)
+// selectBook returns the opening book for the current turn
+func selectBook(game *chess.Game, us UCIState) *Book {
+	if game.Position().Turn() == chess.White {
+		return us.BookWhite
+	}
+	return us.BookBlack
+}
+
+// bookMove returns a move from the opening book when the game is still
+// within the configured book depth and the position is in the book
+func bookMove(game *chess.Game, book *Book, cfg *UCIEngine) *chess.Move {
+	if book == nil || (cfg.BookDepth > 0 && len(game.Moves()) >= cfg.BookDepth) {
+		return nil
+	}
+	return book.Pick(game.Position(), cfg.BookSelect)
+}
+
+// EngMove executes a move using the opening book or the UCI engine
+func EngMove(gs *GameState) string {
+	game := gs.Game
+	eng, engCfg := selectEngine(game, gs.UCI)
+	gs.Ponder = nil
+
+	// Play from the book while the position is covered
+	if move := bookMove(game, selectBook(game, gs.UCI), engCfg); move != nil {
+		if err := game.Move(move); err == nil {
+			if gs.BookPlies == nil {
+				gs.BookPlies = make(map[int]bool)
+			}
+			gs.BookPlies[len(game.Moves())] = true
+			return strings.Repeat(" ", 32)
+		}
+	}
+
+	// Engines without native strength limiting are weakened by uchess
+	if weakened(eng, engCfg) {
+		move := weakMove(eng, engCfg, game.Position())
+		if msg, crashed := engineCrash(gs, eng); crashed {
+			return msg
+		}
+		if move != nil && game.Move(move) == nil {
+			return strings.Repeat(" ", 32)
+		}
+	}
 
-	// Update the engine on the game position
-	cmdPos := uci.CmdPosition{Position: game.Position()}
-	// Params to send to the engine
-	cmdGo := &uci.CmdGo{Depth: engCfg.Depth}
-	cmdGo.MoveTime = engCfg.MoveTime * time.Millisecond
-	// If SearchMoves is specified, include it
-	if engCfg.SearchMoves != "" {
-		cmdGo.SearchMoves = searchMoves(engCfg.SearchMoves)
+	// Run the search, a ponder hit continues the ponder search
+	results, err := eng.Search(game.Position(), searchCmd(engCfg))
+	if msg, crashed := engineCrash(gs, eng); crashed {
+		return msg
 	}
-	// Run the actual commands
-	if err := eng.Run(cmdPos, *cmdGo); err != nil {
+	if err != nil {
 		return "\u26A0 Error. Engine command."
 	}
 	// Fetch the results
-	move := eng.SearchResults().BestMove
+	move := results.BestMove
+	// Translate Chess960 castling
+	if move != nil {
+		if m := findMove(game.Position(), move); m != nil {
+			move = m
+		}
+	}
 	// Validate the move
 	if err := game.Move(move); err != nil {
 		return "\u26A0 Error. Engine move."
 	}
+	// Keep the expected reply for pondering
+	if engCfg.Ponder {
+		gs.Ponder = results.Ponder
+	}
 	// Clear the label
 	return strings.Repeat(" ", 32)
 }
 
-func undoMove(game *chess.Game) *chess.Game {
-	newGame := chess.NewGame()
+// NewGameFEN returns a new game from a FEN, the standard position when the
+// FEN is empty. Games starting elsewhere carry the SetUp and FEN PGN tags
+// and Chess960 games the Variant tag as well
+func NewGameFEN(fen string, chess960 bool) (*chess.Game, error) {
+	if fen == "" {
+		fen = defaultFEN
+	}
+	opt, err := chess.FEN(fen)
+	if err != nil {
+		return nil, err
+	}
+	game := chess.NewGame(opt)
+	fen = game.Position().String()
+	if chess960 {
+		game.AddTagPair("Variant", chess960Variant)
+	}
+	if chess960 || fen != defaultFEN {
+		game.AddTagPair("SetUp", "1")
+		game.AddTagPair("FEN", fen)
+	}
+	return game, nil
+}
+
+// StartGame replaces the current game and records its starting position
+// so that reset and back return to it
+func StartGame(gs *GameState, game *chess.Game) {
+	gs.Game = game
+	gs.StartFEN = game.Positions()[0].String()
+	clearGameData(gs)
+}
+
+// restartGame returns a new game from the starting position
+func restartGame(gs *GameState) *chess.Game {
+	game, err := NewGameFEN(gs.StartFEN, IsChess960(gs.Game))
+	if err != nil {
+		return chess.NewGame()
+	}
+	return game
+}
+
+func undoMove(gs *GameState) *chess.Game {
+	game := gs.Game
+	newGame := restartGame(gs)
 	moves := game.Moves()
 	for i := 0; i < len(moves)-2; i++ {
 		move := moves[i]
@@ -80,24 +195,151 @@ func undoMove(game *chess.Game) *chess.Game {
 	return newGame
 }
 
-func resetGame(game *chess.Game) *chess.Game {
-	newGame := chess.NewGame()
+func resetGame(gs *GameState) *chess.Game {
+	newGame := restartGame(gs)
 	return newGame
 }
 
-func saveGame(game *chess.Game) string {
-	ts := Timestamp()
-	file := fmt.Sprintf("uchess_%v.txt", ts)
-	f, err := os.Create(file)
-	defer f.Close()
-
+// newGame starts a new game from the given FEN or the standard position
+func newGame(gs *GameState, args []string) string {
+	game, err := NewGameFEN(strings.Join(args, " "), false)
 	if err != nil {
+		return "\u26A0 Invalid FEN."
+	}
+	StartGame(gs, game)
+	return strings.Repeat(" ", 80)
+}
+
+// gameNotes converts board annotations into PGN notes
+func gameNotes(annotations map[int][]Annotation) map[int]pgnNote {
+	notes := make(map[int]pgnNote)
+	for ply, a := range annotations {
+		if comment := annotationComment(a); comment != "" {
+			notes[ply] = pgnNote{Comment: comment}
+		}
+	}
+	return notes
+}
+
+// saveGame saves the PGN for the current game. Games saved to an existing
+// file are appended to it
+func saveGame(gs *GameState, args []string) string {
+	if len(args) > 1 {
+		return "\u26A0 Usage: save [file]"
+	}
+	file := fmt.Sprintf("uchess_%v.pgn", Timestamp())
+	if len(args) == 1 {
+		file = args[0]
+	}
+	if err := appendPGN(file, encodePGN(taggedGame(gs), gameNotes(gs.Annotations))); err != nil {
 		return err.Error()
 	}
-	f.WriteString(game.String())
 	return fmt.Sprintf("Saved %v", file)
 }
 
+// loadGame replaces the current game with the first game in a PGN file
+// restoring any board annotations found in its comments
+func loadGame(gs *GameState, args []string) string {
+	if len(args) != 1 {
+		return "\u26A0 Usage: load <file>"
+	}
+	data, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		return "\u26A0 Error. Unable to read file."
+	}
+	games := SplitPGN(string(data))
+	if len(games) == 0 || OpenGame(gs, games[0]) != nil {
+		return "\u26A0 Error. Invalid PGN."
+	}
+	return fmt.Sprintf("Loaded %v", args[0])
+}
+
+// OpenGame replaces the current game with a single PGN game restoring any
+// board annotations found in its comments. Finished games are not
+// recorded again
+func OpenGame(gs *GameState, pgn string) error {
+	game, err := DecodePGN(pgn)
+	if err != nil {
+		return err
+	}
+	StartGame(gs, game)
+	gs.Recorded = game.Outcome() != chess.NoOutcome
+	// Loaded Chess960 games need the engines in Chess960 mode
+	if IsChess960(game) && gs.Config.Chess960 == "" {
+		gs.Config.Chess960 = chess960Random
+		setChess960(gs.UCI)
+	}
+	gs.Annotations = make(map[int][]Annotation)
+	for ply, comment := range pgnComments(pgn) {
+		if notes := parseAnnotations(comment); len(notes) > 0 {
+			gs.Annotations[ply] = notes
+		}
+	}
+	return nil
+}
+
+// annotate toggles a square mark or arrow on the current position
+func annotate(gs *GameState, a Annotation) {
+	if gs.Annotations == nil {
+		gs.Annotations = make(map[int][]Annotation)
+	}
+	ply := len(gs.Game.Moves())
+	gs.Annotations[ply] = toggleAnnotation(gs.Annotations[ply], a)
+}
+
+// optColor returns the color named by the optional argument at idx
+// with green being the default
+func optColor(args []string, idx int) (string, error) {
+	if len(args) <= idx {
+		return MarkGreen, nil
+	}
+	return markColor(args[idx])
+}
+
+// mark toggles a colored mark on a square, i.e., mark e4 red
+func mark(gs *GameState, args []string) string {
+	if len(args) < 1 || len(args) > 2 {
+		return "\u26A0 Usage: mark <square> [color]"
+	}
+	sq, err := parseSquare(args[0])
+	if err != nil {
+		return "\u26A0 Invalid square."
+	}
+	color, err := optColor(args, 1)
+	if err != nil {
+		return "\u26A0 Invalid color. Use red, green, blue or yellow."
+	}
+	annotate(gs, Annotation{color, sq, sq})
+	return strings.Repeat(" ", 80)
+}
+
+// arrow toggles a colored arrow between two squares, i.e., arrow g1f3 blue
+func arrow(gs *GameState, args []string) string {
+	if len(args) < 1 || len(args) > 2 || len(args[0]) != 4 {
+		return "\u26A0 Usage: arrow <from><to> [color]"
+	}
+	from, err := parseSquare(args[0][:2])
+	if err != nil {
+		return "\u26A0 Invalid square."
+	}
+	to, err := parseSquare(args[0][2:])
+	if err != nil || to == from {
+		return "\u26A0 Invalid square."
+	}
+	color, err := optColor(args, 1)
+	if err != nil {
+		return "\u26A0 Invalid color. Use red, green, blue or yellow."
+	}
+	annotate(gs, Annotation{color, from, to})
+	return strings.Repeat(" ", 80)
+}
+
+// unmark clears the annotations on the current position
+func unmark(gs *GameState) string {
+	delete(gs.Annotations, len(gs.Game.Moves()))
+	return strings.Repeat(" ", 80)
+}
+
 func saveImage(game *chess.Game) string {
 	ts := Timestamp()
 	file := fmt.Sprintf("uchess_%v.svg", ts)
@@ -121,41 +363,111 @@ func resign(game *chess.Game) *chess.Game {
 }
 
 func hint(gs *GameState) string {
-	DrawMsgLabel(gs.S, "Thinking...", gs.Theme)
-	Render(gs)
+	gs.emit(EventThinking, "Thinking...", nil)
 	eng := gs.UCI.UciHint
 	engCfg := gs.UCI.CfgHint
-	// Update the engine on the game position
-	cmdPos := uci.CmdPosition{Position: gs.Game.Position()}
-	// Params to send to the engine
-	cmdGo := &uci.CmdGo{Depth: engCfg.Depth}
-	cmdGo.MoveTime = engCfg.MoveTime * time.Millisecond
-	// If SearchMoves is specified, include it
-	if engCfg.SearchMoves != "" {
-		cmdGo.SearchMoves = searchMoves(engCfg.SearchMoves)
-	}
-	// Run the actual commands
-	if err := eng.Run(cmdPos, *cmdGo); err != nil {
+	// Run the search
+	results, err := eng.Search(gs.Game.Position(), searchCmd(engCfg))
+	if err != nil {
 		return "\u26A0 Error. Engine command."
 	}
-	// Take the best move
-	bestMove := eng.SearchResults().BestMove
 	// Success, set the move in the game state
-	gs.Hint = bestMove
+	gs.Hint = results.BestMove
+	if gs.Hint != nil {
+		if m := findMove(gs.Game.Position(), gs.Hint); m != nil {
+			gs.Hint = m
+		}
+	}
+	gs.HintPV = results.Info.PV
 	return strings.Repeat(" ", 80)
 }
 
+// analyzeGame runs the hint engine over every position in the game and
+// saves the annotated PGN in the CWD
+func analyzeGame(gs *GameState) string {
+	progress := func(ply, total int) {
+		gs.emit(EventProgress, fmt.Sprintf("Analyzing %v/%v...%v", ply+1, total, strings.Repeat(" ", 20)), nil)
+	}
+	analysis, err := AnalyzeGame(gs.Game, gs.UCI.UciHint, gs.UCI.CfgHint, progress)
+	if err != nil {
+		return "\u26A0 Error. Engine command."
+	}
+
+	ts := Timestamp()
+	file := fmt.Sprintf("uchess_%v_analysis.pgn", ts)
+	f, err := os.Create(file)
+	defer f.Close()
+
+	if err != nil {
+		return err.Error()
+	}
+	f.WriteString(encodePGN(gs.Game, analysisNotes(gs.Game, analysis, gameNotes(gs.Annotations))))
+	return fmt.Sprintf("%v. Saved %v", analysis.Summary(), file)
+}
+
+// clearGameData clears the state tied to the moves of the current game
+func clearGameData(gs *GameState) {
+	gs.Annotations = nil
+	gs.BookPlies = nil
+	gs.BlunderChecks = 0
+	gs.Recorded = false
+	gs.Ponder = nil
+}
+
+// pruneBookPlies drops book markers for plies past the end of the game
+func pruneBookPlies(book map[int]bool, plies int) {
+	for ply := range book {
+		if ply > plies {
+			delete(book, ply)
+		}
+	}
+}
+
 // ProcessCmd processes a move request or command
 func ProcessCmd(cmd string, gs *GameState) (string, *chess.Game) {
 	cmd = strings.TrimSpace(cmd)
+	args := strings.Fields(cmd)
+	verb := ""
+	if len(args) > 0 {
+		verb, args = args[0], args[1:]
+	}
+
+	// A move held back by the blunder check awaits confirmation
+	if gs.PendingMove != nil {
+		return confirmMove(gs, cmd), gs.Game
+	}
 
-	switch cmd {
+	// Puzzle mode checks moves against the solution
+	if gs.Puzzle != nil {
+		if msg, ok := puzzleCmd(gs, verb, cmd); ok {
+			return msg, gs.Game
+		}
+	}
+
+	switch verb {
 	// Back one turn
 	case "back":
-		return strings.Repeat(" ", 80), undoMove(gs.Game)
+		game := undoMove(gs)
+		gs.Ponder = nil
+		pruneAnnotations(gs.Annotations, len(game.Moves()))
+		pruneBookPlies(gs.BookPlies, len(game.Moves()))
+		return strings.Repeat(" ", 80), game
 		// Save the PGN string
 	case "save":
-		return saveGame(gs.Game), gs.Game
+		return saveGame(gs, args), gs.Game
+		// Load a PGN file
+	case "load":
+		msg := loadGame(gs, args)
+		return msg, gs.Game
+		// Mark a square
+	case "mark":
+		return mark(gs, args), gs.Game
+		// Draw an arrow
+	case "arrow":
+		return arrow(gs, args), gs.Game
+		// Clear marks and arrows from the current position
+	case "unmark":
+		return unmark(gs), gs.Game
 		// SVG snapshot of the current board
 	case "image":
 		return saveImage(gs.Game), gs.Game
@@ -164,15 +476,50 @@ func ProcessCmd(cmd string, gs *GameState) (string, *chess.Game) {
 		return gs.Game.Position().String(), gs.Game
 		// Reset the game
 	case "reset":
-		return strings.Repeat(" ", 80), resetGame(gs.Game)
+		clearGameData(gs)
+		return strings.Repeat(" ", 80), resetGame(gs)
+		// Start a new game, optionally from a FEN
+	case "newgame":
+		msg := newGame(gs, args)
+		return msg, gs.Game
 		// Current player resigns
 	case "resign":
 		return strings.Repeat(" ", 80), resign(gs.Game)
 		// Process a move string
 	case "hint":
 		return hint(gs), gs.Game
+		// Analyze every move of the game
+	case "analyze-game":
+		return analyzeGame(gs), gs.Game
+		// Toggle the blunder check
+	case "blundercheck":
+		return toggleBlunderCheck(gs, args), gs.Game
+		// Show or set the adaptive level
+	case "level":
+		return levelCmd(gs, args), gs.Game
+		// Start a Chess960 game
+	case "chess960":
+		msg := startChess960(gs, args)
+		return msg, gs.Game
+		// Edit the options of an engine
+	case "options":
+		msg := optionsCmd(gs, args)
+		return msg, gs.Game
 	default:
-		if err := gs.Game.MoveStr(cmd); err != nil {
+		move, err := chess.AlgebraicNotation{}.Decode(gs.Game.Position(), cmd)
+		// Castling may be entered with zeros or as the king taking its rook
+		if m := castleInput(gs.Game.Position(), cmd); m != nil {
+			move, err = m, nil
+		}
+		if err != nil || gs.Game.Outcome() != chess.NoOutcome {
+			return "\u26A0 Illegal. Try again.", gs.Game
+		}
+		if gs.Config.BlunderCheck {
+			if msg, held := blunderCheck(gs, move); held {
+				return msg, gs.Game
+			}
+		}
+		if err := gs.Game.Move(move); err != nil {
 			return "\u26A0 Illegal. Try again.", gs.Game
 		}
 	}
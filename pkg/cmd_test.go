@@ -0,0 +1,255 @@
+package uchess
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+const (
+	// startKey is the start position as matched by mock engines
+	startKey = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq"
+	// e4Key is the position after 1. e4
+	e4Key = "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq"
+)
+
+// playedMove returns the last move of the game in UCI notation
+func playedMove(game *chess.Game) string {
+	moves := game.Moves()
+	if len(moves) == 0 {
+		return ""
+	}
+	return moves[len(moves)-1].String()
+}
+
+func TestEngMove(t *testing.T) {
+	cfg := mockEngine(t, "mock", `
+position `+e4Key+`
+info depth 10 score cp 20 pv c7c5 g1f3
+bestmove c7c5 ponder g1f3
+`)
+	gs := newTestState(t, testConfig("human", "cpu"), cfg, cfg, cfg)
+	gs.Game.MoveStr("e4")
+	if msg := EngMove(gs); msg != strings.Repeat(" ", 32) {
+		t.Errorf("EngMove() = %q, want a cleared label", msg)
+	}
+	if got := playedMove(gs.Game); got != "c7c5" {
+		t.Errorf("engine played %v, want c7c5", got)
+	}
+	// The expected reply is only kept for engines which ponder
+	if gs.Ponder != nil {
+		t.Errorf("ponder move %v kept without ponder", gs.Ponder)
+	}
+}
+
+func TestEngMovePonder(t *testing.T) {
+	cfg := mockEngine(t, "mock", "position "+e4Key+"\nbestmove c7c5 ponder g1f3\n")
+	cfg.Ponder = true
+	gs := newTestState(t, testConfig("human", "cpu"), cfg, cfg, cfg)
+	gs.Game.MoveStr("e4")
+	EngMove(gs)
+	if gs.Ponder == nil || gs.Ponder.String() != "g1f3" {
+		t.Errorf("ponder move = %v, want g1f3", gs.Ponder)
+	}
+}
+
+func TestEngMoveIllegal(t *testing.T) {
+	cfg := mockEngine(t, "mock", "position "+e4Key+"\nbestmove e7e4\n")
+	gs := newTestState(t, testConfig("human", "cpu"), cfg, cfg, cfg)
+	gs.Game.MoveStr("e4")
+	if msg := EngMove(gs); msg != "\u26A0 Error. Engine move." {
+		t.Errorf("EngMove() = %q, want the engine move error", msg)
+	}
+	if n := len(gs.Game.Moves()); n != 1 {
+		t.Errorf("game has %v moves, want 1", n)
+	}
+}
+
+func TestEngMoveCrash(t *testing.T) {
+	cfg := mockEngine(t, "mock", "position *\ncrash 3\n")
+	gs := newTestState(t, testConfig("human", "cpu"), cfg, cfg, cfg)
+	gs.Game.MoveStr("e4")
+	// Interactive games carry on and report the restart
+	if msg := EngMove(gs); msg != "\u26A0 Error. Engine command." {
+		t.Errorf("EngMove() = %q, want the engine command error", msg)
+	}
+	if gs.Game.Outcome() != chess.NoOutcome {
+		t.Errorf("outcome %v, want the game to go on", gs.Game.Outcome())
+	}
+	if event := EngineEvent(gs.UCI); !strings.Contains(event, "mock crashed and was restarted") {
+		t.Errorf("EngineEvent() = %q, want the restart", event)
+	}
+}
+
+func TestEngMoveCrashForfeits(t *testing.T) {
+	cfg := mockEngine(t, "mock", "position *\ncrash 3\n")
+	gs := newTestState(t, testConfig("cpu", "cpu"), cfg, cfg, cfg)
+	msg := EngMove(gs)
+	if !strings.Contains(msg, "White forfeits") {
+		t.Errorf("EngMove() = %q, want White to forfeit", msg)
+	}
+	if gs.Game.Outcome() != chess.BlackWon {
+		t.Errorf("outcome %v, want 0-1", gs.Game.Outcome())
+	}
+	if tag := gs.Game.GetTagPair("Termination"); tag == nil || tag.Value != "abandoned" {
+		t.Errorf("termination tag %v, want abandoned", tag)
+	}
+}
+
+func TestEngScore(t *testing.T) {
+	cfg := mockEngine(t, "mock", `
+position `+startKey+`
+info depth 10 score cp 35 pv e2e4
+bestmove e2e4
+
+position `+e4Key+`
+info depth 10 score cp -20 pv c7c5
+bestmove c7c5
+`)
+	gs := newTestState(t, testConfig("human", "cpu"), cfg, cfg, cfg)
+	for _, tc := range []struct {
+		move  string
+		score int
+	}{
+		{"", 35},
+		{"e4", -20},
+	} {
+		if tc.move != "" {
+			gs.Game.MoveStr(tc.move)
+		}
+		score, err := EngScore(gs.Game, gs.UCI, gs.Config)
+		if err != nil || score != tc.score {
+			t.Errorf("after %q: EngScore() = %v, %v, want %v", tc.move, score, err, tc.score)
+		}
+	}
+}
+
+func TestEngScoreNoEngine(t *testing.T) {
+	cfg := mockEngine(t, "mock", "")
+	gs := newTestState(t, testConfig("human", "human"), cfg, cfg, cfg)
+	// Human vs human games start no engine for the score
+	if _, err := EngScore(gs.Game, gs.UCI, gs.Config); err == nil {
+		t.Error("EngScore() succeeded without a running engine")
+	}
+	if gs.UCI.UciHint.Started() {
+		t.Error("the score started the hint engine")
+	}
+}
+
+func TestHint(t *testing.T) {
+	cfg := mockEngine(t, "mock", `
+position `+startKey+`
+info depth 10 score cp 35 pv e2e4 e7e5 g1f3
+bestmove e2e4 ponder e7e5
+`)
+	gs := newTestState(t, testConfig("human", "cpu"), cfg, cfg, cfg)
+	if msg := hint(gs); msg != strings.Repeat(" ", 80) {
+		t.Errorf("hint() = %q, want a cleared label", msg)
+	}
+	if gs.Hint == nil || gs.Hint.String() != "e2e4" {
+		t.Errorf("hint = %v, want e2e4", gs.Hint)
+	}
+	if len(gs.HintPV) != 3 {
+		t.Errorf("hint PV has %v moves, want 3", len(gs.HintPV))
+	}
+}
+
+func TestHintCrash(t *testing.T) {
+	cfg := mockEngine(t, "mock", "position *\ncrash\n")
+	gs := newTestState(t, testConfig("human", "human"), cfg, cfg, cfg)
+	if msg := hint(gs); msg != "\u26A0 Error. Engine command." {
+		t.Errorf("hint() = %q, want the engine command error", msg)
+	}
+	if gs.Hint != nil {
+		t.Errorf("hint = %v after a crash", gs.Hint)
+	}
+}
+
+func TestProcessCmdNewGame(t *testing.T) {
+	cfg := mockEngine(t, "mock", "")
+	gs := newTestState(t, testConfig("human", "human"), cfg, cfg, cfg)
+	gs.Game.MoveStr("e4")
+	fen := "4k3/8/8/8/8/8/8/4K2R w K - 0 1"
+	// The returned game is the one the command started
+	msg, game := ProcessCmd("newgame "+fen, gs)
+	if msg != strings.Repeat(" ", 80) {
+		t.Errorf("ProcessCmd() = %q, want a cleared label", msg)
+	}
+	if game != gs.Game || game.Position().String() != fen {
+		t.Errorf("ProcessCmd() returned the game at %v, want the new game at %v", game.Position(), fen)
+	}
+}
+
+func TestStartFEN(t *testing.T) {
+	fen := "4k3/8/8/8/8/8/8/4K2R w K - 0 1"
+	chess960, err := NewChess960Game(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name     string
+		game     *chess.Game
+		moves    []string
+		cmd      string
+		plies    int
+		chess960 bool
+	}{
+		{"back", nil, []string{"Kd2", "Kd7", "Ke2", "Ke7"}, "back", 2, false},
+		{"back to the start", nil, []string{"Kd2", "Kd7"}, "back", 0, false},
+		{"reset", nil, []string{"Kd2", "Kd7", "Ke2"}, "reset", 0, false},
+		// Chess960 games stay Chess960 games
+		{"chess960 reset", chess960, []string{"e4", "e5"}, "reset", 0, true},
+		{"chess960 back", chess960, []string{"e4", "e5", "d4", "d5"}, "back", 2, true},
+	} {
+		gs := &GameState{Config: testConfig("human", "human")}
+		game := tc.game
+		if game == nil {
+			if game, err = NewGameFEN(fen, false); err != nil {
+				t.Fatal(err)
+			}
+		}
+		StartGame(gs, cloneGame(game))
+		start := gs.Game.Position().String()
+		for _, m := range tc.moves {
+			if err := gs.Game.MoveStr(m); err != nil {
+				t.Fatal(err)
+			}
+		}
+		_, game = ProcessCmd(tc.cmd, gs)
+		tag := game.GetTagPair("FEN")
+		switch {
+		case game.Positions()[0].String() != start:
+			t.Errorf("%v: game starts at %v, want %v", tc.name, game.Positions()[0], start)
+		case len(game.Moves()) != tc.plies:
+			t.Errorf("%v: game has %v moves, want %v", tc.name, len(game.Moves()), tc.plies)
+		case tag == nil || tag.Value != start:
+			t.Errorf("%v: FEN tag %v, want %v", tc.name, tag, start)
+		case IsChess960(game) != tc.chess960:
+			t.Errorf("%v: Chess960 %v, want %v", tc.name, IsChess960(game), tc.chess960)
+		}
+	}
+}
+
+func TestSaveLoadStartFEN(t *testing.T) {
+	fen := "4k3/8/8/8/8/8/8/4K2R w K - 0 1"
+	gs := &GameState{Config: testConfig("human", "human")}
+	ProcessCmd("newgame "+fen, gs)
+	gs.Game.MoveStr("Kd2")
+	gs.Game.MoveStr("Kd7")
+	file := filepath.Join(t.TempDir(), "game.pgn")
+	ProcessCmd("save "+file, gs)
+
+	gs = &GameState{Config: testConfig("human", "human")}
+	if msg, _ := ProcessCmd("load "+file, gs); msg != "Loaded "+file {
+		t.Fatalf("ProcessCmd(load) = %q", msg)
+	}
+	// The loaded game goes back to the saved start position
+	if gs.StartFEN != fen || len(gs.Game.Moves()) != 2 {
+		t.Errorf("loaded game starts at %v with %v moves, want %v and 2 moves", gs.StartFEN, len(gs.Game.Moves()), fen)
+	}
+	if _, game := ProcessCmd("reset", gs); game.Position().String() != fen {
+		t.Errorf("reset to %v, want %v", game.Position(), fen)
+	}
+}
@@ -1,12 +1,15 @@
 package uchess
 
 import (
+	"bytes"
 	"embed"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"path"
 	"path/filepath"
+	"reflect"
+	"sort"
 )
 
 //go:embed themes/*
@@ -14,17 +17,26 @@ var content embed.FS
 
 // Config defines the configurable parameters for UCI
 type Config struct {
-	UCIWhite    string      `json:"uciWhite"`
-	UCIBlack    string      `json:"uciBlack"`
-	UCIHint     string      `json:"uciHint"`
-	UCIEngines  []UCIEngine `json:"uciEngines"`
-	FEN         string      `json:"fen"`
-	ActiveTheme string      `json:"activeTheme"`
-	Themes      []ThemeHex  `json:"theme"`
-	WhitePiece  string      `json:"whitePiece"`
-	BlackPiece  string      `json:"blackPiece"`
-	WhiteName   string      `json:"whiteName"`
-	BlackName   string      `json:"blackName"`
+	UCIWhite         string      `json:"uciWhite"`
+	UCIBlack         string      `json:"uciBlack"`
+	UCIHint          string      `json:"uciHint"`
+	UCIEngines       []UCIEngine `json:"uciEngines"`
+	FEN              string      `json:"fen"`
+	ActiveTheme      string      `json:"activeTheme"`
+	Themes           []ThemeHex  `json:"theme"`
+	WhitePiece       string      `json:"whitePiece"`
+	BlackPiece       string      `json:"blackPiece"`
+	WhiteName        string      `json:"whiteName"`
+	BlackName        string      `json:"blackName"`
+	BlunderCheck     bool        `json:"blunderCheck"`
+	BlunderThreshold int         `json:"blunderThreshold"`
+	SyzygyPath       string      `json:"syzygyPath"`
+	Chess960         string      `json:"chess960"`
+	PGNDatabase      string      `json:"pgnDatabase"`
+	Resume           bool        `json:"-"` // Set by the -resume flag
+	Adaptive         bool        `json:"adaptive"`
+	File             string      `json:"-"` // Config file the config was read from
+	ShowPonder       bool        `json:"showPonder"`
 }
 
 // HasTheme returns a bool indicating whether the config
@@ -55,6 +67,15 @@ var defaultEngine = UCIEngine{
 	"",          // SearchMoves
 	100,         // MoveTime
 	DefaultOptions,
+	"",                  // Book
+	0,                   // BookDepth
+	BookRandom,          // BookSelect
+	false,               // LimitStrength
+	0,                   // Elo
+	protocolUCI,         // Protocol
+	[]string{},          // Args
+	map[string]string{}, // Env
+	"",                  // WorkDir
 }
 
 // defaultFEN is the default board position
@@ -86,17 +107,26 @@ func ReadThemes() []ThemeHex {
 
 // DefaultConfig defines the default configuration
 var defaultConfig = Config{
-	"stockfish",   // UCIWhite
-	"stockfish",   // UCIBlack
-	"stockfish",   // UCIHint
-	[]UCIEngine{}, // UCIEngine
-	defaultFEN,    // FEN
-	"basic",       // ActiveTheme
-	[]ThemeHex{},  // Themes
-	"human",       // WhitePiece
-	"cpu",         // BlackPiece
-	"",            // WhiteName
-	"",            // BlackName
+	"stockfish",             // UCIWhite
+	"stockfish",             // UCIBlack
+	"stockfish",             // UCIHint
+	[]UCIEngine{},           // UCIEngine
+	defaultFEN,              // FEN
+	"basic",                 // ActiveTheme
+	[]ThemeHex{},            // Themes
+	"human",                 // WhitePiece
+	"cpu",                   // BlackPiece
+	"",                      // WhiteName
+	"",                      // BlackName
+	false,                   // BlunderCheck
+	defaultBlunderThreshold, // BlunderThreshold
+	"",                      // SyzygyPath
+	"",                      // Chess960
+	"",                      // PGNDatabase
+	false,                   // Resume
+	false,                   // Adaptive
+	"",                      // File
+	false,                   // ShowPonder
 }
 
 // MakeDefault creates the default config
@@ -153,5 +183,74 @@ func ReadConfig(file string) Config {
 		allThemes = append(allThemes, theme)
 	}
 	config.Themes = allThemes
+	config.File = file
 	return config
 }
+
+// SaveEngineOptions writes option values to the config of the named engine
+// in the config file. The file is read again and only the engine fields
+// the values change are patched, so keys uchess does not know are kept
+func SaveEngineOptions(file, name string, values map[string]string) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	var raw map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&raw); err != nil {
+		return err
+	}
+	engines, _ := raw["uciEngines"].([]interface{})
+	var entry map[string]interface{}
+	for _, e := range engines {
+		if m, ok := e.(map[string]interface{}); ok && m["name"] == name {
+			entry = m
+		}
+	}
+	if entry == nil {
+		return fmt.Errorf("config: engine %v not found", name)
+	}
+	// The values are applied to the engine config, which keeps options
+	// with a config key of their own under that key
+	var cfg UCIEngine
+	if err := remarshal(entry, &cfg); err != nil {
+		return err
+	}
+	var before map[string]interface{}
+	if err := remarshal(&cfg, &before); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(values))
+	for k := range values {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		setOption(&cfg, k, values[k])
+	}
+	var after map[string]interface{}
+	if err := remarshal(&cfg, &after); err != nil {
+		return err
+	}
+	for k, v := range after {
+		if !reflect.DeepEqual(v, before[k]) {
+			entry[k] = v
+		}
+	}
+	data, err = json.MarshalIndent(raw, "", "    ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, data, 0644)
+}
+
+// remarshal converts between JSON values by encoding src and decoding the
+// result into dst
+func remarshal(src, dst interface{}) error {
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
@@ -0,0 +1,64 @@
+package uchess
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSaveEngineOptions(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "uchess.json")
+	config := `{
+    "uciWhite": "stockfish",
+    "custom": {"kept": true},
+    "uciEngines": [
+        {"name": "other", "engine": "other", "options": [{"name": "Skill Level", "value": "1"}]},
+        {"name": "stockfish", "engine": "stockfish", "hash": 64, "note": "kept",
+         "options": [{"name": "Skill Level", "value": "3"}]}
+    ]
+}`
+	if err := ioutil.WriteFile(file, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	values := map[string]string{"Skill Level": "10", "Hash": "256", "Contempt": "5"}
+	if err := SaveEngineOptions(file, "stockfish", values); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got["custom"], map[string]interface{}{"kept": true}) {
+		t.Errorf("unknown key custom = %v, want it kept", got["custom"])
+	}
+	engines := got["uciEngines"].([]interface{})
+	other, eng := engines[0].(map[string]interface{}), engines[1].(map[string]interface{})
+	if eng["note"] != "kept" || eng["hash"] != 256.0 {
+		t.Errorf("engine note %v, hash %v, want kept and 256", eng["note"], eng["hash"])
+	}
+	// Fields the values do not change are not written
+	if _, ok := eng["depth"]; ok {
+		t.Error("unchanged engine field depth written")
+	}
+	want := []interface{}{
+		map[string]interface{}{"name": "Skill Level", "value": "10"},
+		map[string]interface{}{"name": "Contempt", "value": "5"},
+	}
+	if !reflect.DeepEqual(eng["options"], want) {
+		t.Errorf("options %v, want %v", eng["options"], want)
+	}
+	if len(other) != 3 {
+		t.Errorf("other engine %v changed", other)
+	}
+
+	if err := SaveEngineOptions(file, "missing", values); err == nil {
+		t.Error("SaveEngineOptions() succeeded for a missing engine")
+	}
+}
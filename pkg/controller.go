@@ -0,0 +1,173 @@
+package uchess
+
+import (
+	"fmt"
+
+	"github.com/notnil/chess"
+)
+
+// Event kinds
+const (
+	EventMessage  = "message"  // A message for the players
+	EventThinking = "thinking" // An engine started searching
+	EventProgress = "progress" // Progress of a long running command
+	EventMove     = "move"     // A move was played
+	EventGameOver = "gameover" // The game ended
+	EventUpdate   = "update"   // The state settled after a command
+)
+
+// Event reports something that happened in the game
+type Event struct {
+	Kind string      // Event kind
+	Msg  string      // Message for the players
+	Move *chess.Move // Move played
+}
+
+// emit passes an event to the subscribers, if any
+func (gs *GameState) emit(kind, msg string, move *chess.Move) {
+	if gs.notify != nil {
+		gs.notify(Event{kind, msg, move})
+	}
+}
+
+// Controller plays a game without a user interface. It takes the commands
+// of the human players, lets the engines move and reports what happens to
+// its subscribers, so the same game runs in the terminal, headless or in
+// tests
+type Controller struct {
+	State       *GameState
+	subscribers []func(Event)
+	outcome     chess.Outcome // Outcome last reported
+}
+
+// NewController returns a controller for the game state
+func NewController(gs *GameState) *Controller {
+	c := &Controller{State: gs, outcome: gs.Game.Outcome()}
+	gs.notify = c.publish
+	return c
+}
+
+// Subscribe registers f to receive the events of the game. Events are
+// delivered in order on the goroutine running the controller
+func (c *Controller) Subscribe(f func(Event)) {
+	c.subscribers = append(c.subscribers, f)
+}
+
+// publish passes an event to every subscriber
+func (c *Controller) publish(e Event) {
+	for _, f := range c.subscribers {
+		f(e)
+	}
+}
+
+// Start scores the position and lets the CPU move when it has the first
+// move. Warnings about the options of engines that started are reported
+func (c *Controller) Start() {
+	gs := c.State
+	c.engineMove()
+	UpdateScore(gs)
+	if warning := OptionWarning(gs.UCI); warning != "" {
+		gs.emit(EventMessage, warning, nil)
+	}
+	gs.emit(EventUpdate, "", nil)
+}
+
+// Submit processes a command of the human player to move, then lets the
+// CPU reply. In CPU matches the command is ignored and the next engine
+// move is played
+func (c *Controller) Submit(cmd string) {
+	gs := c.State
+	if IsInteractive(gs.Config) {
+		plies := len(gs.Game.Moves())
+		// Reset hints when new commands come through
+		gs.Hint = nil
+		gs.HintPV = nil
+		var msg string
+		msg, gs.Game = ProcessCmd(cmd, gs)
+		setChecks(gs)
+		c.moved(plies)
+		gs.emit(EventMessage, msg, nil)
+		// Show the move before the CPU replies
+		gs.emit(EventUpdate, "", nil)
+	}
+	c.engineMove()
+	c.settle()
+}
+
+// Play plays the engine moves until the game ends or an engine fails to
+// move, i.e., to run a CPU match headless
+func (c *Controller) Play() {
+	gs := c.State
+	for gs.Game.Outcome() == chess.NoOutcome && IsCPU(gs.Game.Position().Turn(), gs.Config) {
+		plies := len(gs.Game.Moves())
+		c.Submit("")
+		if len(gs.Game.Moves()) == plies {
+			return
+		}
+	}
+}
+
+// engineMove lets the CPU move if it is its turn
+func (c *Controller) engineMove() {
+	gs := c.State
+	if gs.Game.Outcome() != chess.NoOutcome || !IsCPU(gs.Game.Position().Turn(), gs.Config) {
+		return
+	}
+	plies := len(gs.Game.Moves())
+	gs.emit(EventThinking, "Thinking...", nil)
+	msg := EngMove(gs)
+	setChecks(gs)
+	c.moved(plies)
+	gs.emit(EventMessage, msg, nil)
+}
+
+// moved reports the moves played since the game had the given number of
+// plies
+func (c *Controller) moved(plies int) {
+	moves := c.State.Game.Moves()
+	for i := plies; i < len(moves); i++ {
+		c.State.emit(EventMove, "", moves[i])
+	}
+}
+
+// settle journals and scores the game after a command and reports what
+// became of it: the end of the game, its recording and engine trouble
+func (c *Controller) settle() {
+	gs := c.State
+	msg := ""
+	// If the game is still in play, update the score
+	if gs.Game.Outcome() == chess.NoOutcome {
+		// Journal the game after every move for crash recovery
+		SaveJournal(gs)
+		UpdateScore(gs)
+	}
+	// Finished games go to the PGN database
+	if recorded := RecordGame(gs); msg == "" {
+		msg = recorded
+	}
+	// Report configured options rejected by engines that just started
+	if warning := OptionWarning(gs.UCI); warning != "" {
+		msg = warning
+	}
+	// Report engines that crashed and were restarted
+	if event := EngineEvent(gs.UCI); event != "" {
+		msg = event
+	}
+	// The CPU opponent ponders while the human is to move
+	StartPonder(gs)
+	if outcome := gs.Game.Outcome(); outcome != c.outcome {
+		c.outcome = outcome
+		if outcome != chess.NoOutcome {
+			gs.emit(EventGameOver, fmt.Sprintf("%v (%v)", outcome, gs.Game.Method()), nil)
+		}
+	}
+	if msg != "" {
+		gs.emit(EventMessage, msg, nil)
+	}
+	gs.emit(EventUpdate, "", nil)
+}
+
+// setChecks indicates if either color is in check
+func setChecks(gs *GameState) {
+	gs.CheckWhite, gs.CheckBlack = InCheck(gs.Game)
+}
@@ -0,0 +1,78 @@
+package uchess
+
+import (
+	"strings"
+	"testing"
+)
+
+// recordEvents subscribes to the events of the controller
+func recordEvents(c *Controller) *[]Event {
+	events := make([]Event, 0)
+	c.Subscribe(func(e Event) {
+		events = append(events, e)
+	})
+	return &events
+}
+
+// eventKinds lists the kinds of the events along with the moves played
+func eventKinds(events []Event) string {
+	kinds := make([]string, 0, len(events))
+	for _, e := range events {
+		if e.Move != nil {
+			kinds = append(kinds, e.Kind+" "+e.Move.String())
+			continue
+		}
+		kinds = append(kinds, e.Kind)
+	}
+	return strings.Join(kinds, ", ")
+}
+
+func TestControllerSubmit(t *testing.T) {
+	cfg := mockEngine(t, "mock", "position "+e4Key+"\nbestmove c7c5\n")
+	c := NewController(newTestState(t, testConfig("human", "cpu"), cfg, cfg, cfg))
+	events := recordEvents(c)
+	c.Submit("e4")
+	want := "move e2e4, message, update, thinking, move c7c5, message, update"
+	if got := eventKinds(*events); got != want {
+		t.Errorf("events %v, want %v", got, want)
+	}
+}
+
+func TestControllerHint(t *testing.T) {
+	cfg := mockEngine(t, "mock", "position "+startKey+"\ninfo depth 1 score cp 35 pv e2e4\nbestmove e2e4\n")
+	c := NewController(newTestState(t, testConfig("human", "cpu"), cfg, cfg, cfg))
+	events := recordEvents(c)
+	c.Submit("hint")
+	// The search is reported before it blocks
+	if got := eventKinds(*events); !strings.HasPrefix(got, "thinking, message, update") {
+		t.Errorf("events %v, want the search reported first", got)
+	}
+	if c.State.Hint == nil || c.State.Hint.String() != "e2e4" {
+		t.Errorf("hint = %v, want e2e4", c.State.Hint)
+	}
+}
+
+func TestControllerPlay(t *testing.T) {
+	// Black mates after 1. f3 e5 2. g4
+	cfg := mockEngine(t, "mock", "position rnbqkbnr/pppp1ppp/8/4p3/6P1/5P2/PPPPP2P/RNBQKBNR b KQkq\nbestmove d8h4\n")
+	gs := newTestState(t, testConfig("cpu", "cpu"), cfg, cfg, cfg)
+	for _, m := range []string{"f3", "e5", "g4"} {
+		gs.Game.MoveStr(m)
+	}
+
+	c := NewController(gs)
+	events := recordEvents(c)
+	c.Play()
+	over := make([]string, 0)
+	for _, e := range *events {
+		if e.Kind == EventGameOver {
+			over = append(over, e.Msg)
+		}
+	}
+	if len(over) != 1 || over[0] != "0-1 (Checkmate)" {
+		t.Errorf("game over events %q, want 0-1 (Checkmate)", over)
+	}
+	if !gs.Recorded {
+		t.Error("the finished game was not recorded")
+	}
+}
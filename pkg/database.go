@@ -0,0 +1,335 @@
+package uchess
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+const (
+	// defaultEvent is the PGN Event tag of games played in uchess
+	defaultEvent = "uchess game"
+	// pgnUnknown is the PGN value of an unknown tag
+	pgnUnknown = "?"
+	// gameStoreFile collects every finished game in the app dir
+	gameStoreFile = "games.pgn"
+)
+
+// site returns the PGN Site tag, which is the host name of the machine
+func site() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		return pgnUnknown
+	}
+	return host
+}
+
+// engineTag describes the engine and search settings playing a side,
+// including custom options such as the skill level
+func engineTag(cfg *UCIEngine) string {
+	settings := []string{fmt.Sprintf("depth %v", cfg.Depth), fmt.Sprintf("movetime %vms", int64(cfg.MoveTime))}
+	for _, opt := range cfg.Options {
+		settings = append(settings, opt.Name+" "+opt.Value)
+	}
+	return fmt.Sprintf("%v (%v)", cfg.Name, strings.Join(settings, ", "))
+}
+
+// playerName returns the name for the White or Black tag. A CPU side
+// without a configured name goes by the name its engine declared, or by
+// the name of its engine config before the engine has started
+func playerName(name, piece string, eng Engine, cfg *UCIEngine) string {
+	if name != "" || piece != "cpu" {
+		return name
+	}
+	if eng != nil {
+		if id := eng.ID()["name"]; id != "" {
+			return id
+		}
+	}
+	if cfg != nil {
+		return cfg.Name
+	}
+	return ""
+}
+
+// termination returns the PGN Termination tag for the game. Finished games
+// keep a tag set when they ended, i.e., by adjudication, but not the
+// unterminated tag of a save made during play
+func termination(game *chess.Game) string {
+	if game.Outcome() == chess.NoOutcome {
+		return "unterminated"
+	}
+	if tag := game.GetTagPair("Termination"); tag != nil && tag.Value != "unterminated" {
+		return tag.Value
+	}
+	return "normal"
+}
+
+// taggedGame returns a copy of the game with the seven tag roster along
+// with the termination, time control and engine tags. Tags already
+// present, i.e., in a loaded game, are kept apart from the result and
+// termination
+func taggedGame(gs *GameState) *chess.Game {
+	game := cloneGame(gs.Game)
+	addDefault := func(k, v string) {
+		if v == "" {
+			v = pgnUnknown
+		}
+		if game.GetTagPair(k) == nil {
+			game.AddTagPair(k, v)
+		}
+	}
+
+	addDefault("Event", defaultEvent)
+	addDefault("Site", site())
+	addDefault("Date", time.Now().Format("2006.01.02"))
+	addDefault("Round", "-")
+	addDefault("White", playerName(gs.Config.WhiteName, gs.Config.WhitePiece, gs.UCI.UciWhite, gs.UCI.CfgWhite))
+	addDefault("Black", playerName(gs.Config.BlackName, gs.Config.BlackPiece, gs.UCI.UciBlack, gs.UCI.CfgBlack))
+	game.AddTagPair("Result", string(game.Outcome()))
+	game.AddTagPair("Termination", termination(game))
+	// uchess does not keep a clock
+	addDefault("TimeControl", "-")
+	if gs.Config.WhitePiece == "cpu" && gs.UCI.CfgWhite != nil {
+		addDefault("WhiteEngine", engineTag(gs.UCI.CfgWhite))
+	}
+	if gs.Config.BlackPiece == "cpu" && gs.UCI.CfgBlack != nil {
+		addDefault("BlackEngine", engineTag(gs.UCI.CfgBlack))
+	}
+	addOpeningTags(game)
+	return game
+}
+
+// appendPGN adds a game to the end of a PGN file, creating the file when
+// it does not exist
+func appendPGN(file, pgn string) error {
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Games are separated by a blank line
+	if info, err := f.Stat(); err == nil && info.Size() > 0 {
+		if _, err := f.WriteString("\n"); err != nil {
+			return err
+		}
+	}
+	_, err = f.WriteString(pgn)
+	return err
+}
+
+// GameStorePath returns the location of the game store
+func GameStorePath() string {
+	return filepath.Join(AppDir(), gameStoreFile)
+}
+
+// RecordGame appends a finished game to the game store and to the PGN
+// database when one is configured. Each game is recorded once, and in
+// adaptive mode the level is adjusted afterwards. Games under review are
+// already in the store
+func RecordGame(gs *GameState) string {
+	if gs.Puzzle != nil || gs.Reviewing || gs.Recorded || gs.Game.Outcome() == chess.NoOutcome {
+		return ""
+	}
+	gs.Recorded = true
+	// Finished games are no longer resumable
+	ClearJournal()
+	pgn := encodePGN(taggedGame(gs), gameNotes(gs.Annotations))
+	// The tags record the level the game was played at
+	msgs := make([]string, 0)
+	if level := adaptLevel(gs); level != "" {
+		msgs = append(msgs, level)
+	}
+
+	if err := os.MkdirAll(AppDir(), 0755); err != nil {
+		return "\u26A0 Error. Unable to write the game store."
+	}
+	if err := appendPGN(GameStorePath(), pgn); err != nil {
+		return "\u26A0 Error. Unable to write the game store."
+	}
+	if file := gs.Config.PGNDatabase; file != "" {
+		if err := appendPGN(file, pgn); err != nil {
+			return fmt.Sprintf("\u26A0 Error. Unable to write %v.", file)
+		}
+		msgs = append([]string{fmt.Sprintf("Game added to %v", file)}, msgs...)
+	}
+	return strings.Join(msgs, ". ")
+}
+
+// GameRecord is a game in the game store
+type GameRecord struct {
+	Index int // Position in the store starting at 1
+	PGN   string
+	Tags  map[string]string
+}
+
+// tagUnescaper reverses tagEscaper
+var tagUnescaper = strings.NewReplacer(`\\`, `\`, `\"`, `"`)
+
+// ReadGameStore reads every game of a PGN file along with its tags
+func ReadGameStore(file string) ([]GameRecord, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]GameRecord, 0)
+	for i, pgn := range SplitPGN(string(data)) {
+		r := GameRecord{Index: i + 1, PGN: pgn, Tags: make(map[string]string)}
+		var scan pgnScanner
+		for _, line := range strings.Split(pgn, "\n") {
+			line = strings.TrimSpace(line)
+			if !scan.tag(line) {
+				continue
+			}
+			m := tagRegex.FindStringSubmatch(line)
+			r.Tags[m[1]] = tagUnescaper.Replace(m[2])
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
+
+// HumanColor returns the color played by the human in a game against an
+// engine. NoColor is returned for any other game
+func (r GameRecord) HumanColor() chess.Color {
+	_, whiteCPU := r.Tags["WhiteEngine"]
+	_, blackCPU := r.Tags["BlackEngine"]
+	switch {
+	case blackCPU && !whiteCPU:
+		return chess.White
+	case whiteCPU && !blackCPU:
+		return chess.Black
+	}
+	return chess.NoColor
+}
+
+// Opponent returns the engine the human played against
+func (r GameRecord) Opponent() string {
+	switch r.HumanColor() {
+	case chess.White:
+		return r.Tags["BlackEngine"]
+	case chess.Black:
+		return r.Tags["WhiteEngine"]
+	}
+	return ""
+}
+
+// HumanResult returns win, loss or draw from the human's point of view, or
+// an empty string for unfinished games and games without one human
+func (r GameRecord) HumanResult() string {
+	color := r.HumanColor()
+	switch {
+	case color == chess.NoColor:
+		return ""
+	case r.Tags["Result"] == "1/2-1/2":
+		return "draw"
+	case r.Tags["Result"] == "1-0" && color == chess.White,
+		r.Tags["Result"] == "0-1" && color == chess.Black:
+		return "win"
+	case r.Tags["Result"] == "1-0", r.Tags["Result"] == "0-1":
+		return "loss"
+	}
+	return ""
+}
+
+// GameFilter selects games from the store. Empty fields match any game
+type GameFilter struct {
+	Engine  string // Substring of the opponent engine
+	Result  string // win, loss, draw or a PGN result
+	Opening string // Substring of the ECO code or opening name
+	Color   string // Color played by the human
+	From    string // First date, i.e., 2021-03-01
+	To      string // Last date
+}
+
+// containsFold reports whether substr is within s ignoring case
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// pgnDate converts a date to the PGN date format
+func pgnDate(date string) string {
+	return strings.NewReplacer("-", ".", "/", ".").Replace(date)
+}
+
+// Match returns a bool indicating whether the game passes the filter
+func (f GameFilter) Match(r GameRecord) bool {
+	if f.Engine != "" && !containsFold(r.Opponent(), f.Engine) {
+		return false
+	}
+	if f.Result != "" && f.Result != r.HumanResult() && f.Result != r.Tags["Result"] {
+		return false
+	}
+	if f.Opening != "" && !containsFold(r.Tags["ECO"]+" "+r.Tags["Opening"], f.Opening) {
+		return false
+	}
+	if f.Color != "" && !strings.EqualFold(f.Color, r.HumanColor().Name()) {
+		return false
+	}
+	date := r.Tags["Date"]
+	if f.From != "" && date < pgnDate(f.From) {
+		return false
+	}
+	// Dates are compared as strings, a prefix match keeps partial dates
+	if f.To != "" && date > pgnDate(f.To) && !strings.HasPrefix(date, pgnDate(f.To)) {
+		return false
+	}
+	return true
+}
+
+// EngineStats summarizes the human's results against one engine setup
+type EngineStats struct {
+	Opponent string
+	Games    int
+	Wins     int
+	Draws    int
+	Losses   int
+}
+
+// Score returns the human's score in percent
+func (s EngineStats) Score() float64 {
+	if s.Games == 0 {
+		return 0
+	}
+	return 100 * (float64(s.Wins) + float64(s.Draws)/2) / float64(s.Games)
+}
+
+// GameStats totals the finished games against each engine setup, which
+// includes the engine's skill settings
+func GameStats(records []GameRecord) []EngineStats {
+	byOpponent := make(map[string]*EngineStats)
+	for _, r := range records {
+		result := r.HumanResult()
+		if result == "" {
+			continue
+		}
+		s, ok := byOpponent[r.Opponent()]
+		if !ok {
+			s = &EngineStats{Opponent: r.Opponent()}
+			byOpponent[r.Opponent()] = s
+		}
+		s.Games++
+		switch result {
+		case "win":
+			s.Wins++
+		case "draw":
+			s.Draws++
+		case "loss":
+			s.Losses++
+		}
+	}
+
+	stats := make([]EngineStats, 0, len(byOpponent))
+	for _, s := range byOpponent {
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Opponent < stats[j].Opponent })
+	return stats
+}
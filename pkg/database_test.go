@@ -0,0 +1,66 @@
+package uchess
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecordGameAfterSave(t *testing.T) {
+	os.Remove(GameStorePath())
+	cfg := mockEngine(t, "mock", "")
+	gs := newTestState(t, testConfig("human", "human"), cfg, cfg, cfg)
+	gs.Game.MoveStr("f3")
+	gs.Game.MoveStr("e5")
+
+	// Saving during play tags the saved copy only
+	file := filepath.Join(t.TempDir(), "saved.pgn")
+	saveGame(gs, []string{file})
+	if tag := gs.Game.GetTagPair("Termination"); tag != nil {
+		t.Errorf("save tagged the game with %v", tag)
+	}
+	saved, err := ReadGameStore(file)
+	if err != nil || len(saved) != 1 || saved[0].Tags["Termination"] != "unterminated" {
+		t.Fatalf("saved games %v, %v, want one unterminated game", saved, err)
+	}
+
+	gs.Game.MoveStr("g4")
+	gs.Game.MoveStr("Qh4")
+	RecordGame(gs)
+	records, err := ReadGameStore(GameStorePath())
+	if err != nil || len(records) != 1 {
+		t.Fatalf("game store %v, %v, want one game", records, err)
+	}
+	if tags := records[0].Tags; tags["Termination"] != "normal" || tags["Result"] != "0-1" {
+		t.Errorf("recorded termination %q, result %q, want normal 0-1", tags["Termination"], tags["Result"])
+	}
+
+	// A stale tag from an earlier save is replaced as well
+	gs.Game.AddTagPair("Termination", "unterminated")
+	if got := termination(gs.Game); got != "normal" {
+		t.Errorf("termination() = %q, want normal", got)
+	}
+}
+
+func TestTaggedGameEngineNames(t *testing.T) {
+	white := mockEngine(t, "white", "id name Mock 1.0\n")
+	black := mockEngine(t, "black", "")
+	config := testConfig("cpu", "cpu")
+	config.WhiteName, config.BlackName = "", ""
+	gs := newTestState(t, config, white, black, black)
+	if err := gs.UCI.UciWhite.Start(); err != nil {
+		t.Fatal(err)
+	}
+	// CPU sides without a name go by the engine's id name or config name
+	game := taggedGame(gs)
+	for tag, want := range map[string]string{"White": "Mock 1.0", "Black": "black"} {
+		if got := game.GetTagPair(tag); got == nil || got.Value != want {
+			t.Errorf("%v tag %v, want %v", tag, got, want)
+		}
+	}
+	// Configured names are kept
+	gs.Config.WhiteName = "Stockfish"
+	if got := taggedGame(gs).GetTagPair("White"); got == nil || got.Value != "Stockfish" {
+		t.Errorf("White tag %v, want Stockfish", got)
+	}
+}
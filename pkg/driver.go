@@ -0,0 +1,294 @@
+package uchess
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/uci"
+)
+
+// Engine protocols, selected by the protocol key of an engine config
+const (
+	protocolUCI    = "uci"
+	protocolCECP   = "cecp"
+	protocolXBoard = "xboard"
+)
+
+// Engine is a chess engine run by uchess. Searches take the limits of a
+// UCI go command and answer with UCI search results whatever the protocol
+// the engine speaks
+type Engine interface {
+	SetOption(name, value string) error                                     // Set an option
+	Press(name string) error                                                // Press a button option
+	NewGame() error                                                         // Start a new game
+	Search(pos *chess.Position, cmdGo uci.CmdGo) (uci.SearchResults, error) // Search for the best move
+	Ponder(pos *chess.Position, reply *chess.Move, cmdGo uci.CmdGo) error   // Think on the expected reply
+	Pondering() bool                                                        // A ponder search runs
+	Lines() []uci.Info                                                      // Lines of the last search
+	ID() map[string]string                                                  // Name and author
+	Options() ([]EngineOption, error)                                       // Declared options
+	Warnings() []string                                                     // Problems with the configured options
+	Started() bool                                                          // The engine process runs
+	Event() string                                                          // Last restart, cleared once read
+	Start() error                                                           // Start the engine now
+	Close() error                                                           // Shut the engine down
+}
+
+// driver is an engine run by one of the protocol drivers of uchess
+type driver interface {
+	Engine
+	probe() error          // Run the handshake without configuring the engine
+	newWarnings() []string // Warnings not reported yet
+}
+
+// protocol is the part of an engine driver speaking the engine's protocol.
+// Its methods are called with the engine locked
+type protocol interface {
+	handshake() error                    // Start the process and read the id and options
+	sendOption(name, value string) error // Send an option value
+	press(name string) error             // Press a button option
+	sync() error                         // Wait for earlier commands to finish
+	newGame() error                      // Start a new game
+	idle() error                         // End any background search
+}
+
+// NewEngine returns an engine for the config, which is started when it is
+// first used
+func NewEngine(cfg *UCIEngine, config Config) Engine {
+	return newDriver(cfg, config)
+}
+
+// newDriver returns the protocol driver for the config
+func newDriver(cfg *UCIEngine, config Config) driver {
+	switch strings.ToLower(cfg.Protocol) {
+	case protocolCECP, protocolXBoard:
+		return newCECPDriver(cfg, config)
+	}
+	return newUCIDriver(cfg, config)
+}
+
+// StartEngine starts an engine and configures it for the game
+func StartEngine(cfg *UCIEngine, config Config) (Engine, error) {
+	eng := NewEngine(cfg, config)
+	if err := eng.Start(); err != nil {
+		return nil, err
+	}
+	return eng, nil
+}
+
+// engineBase holds the engine state shared by the protocol drivers: the
+// process, the handshake, the options sent for replay and the warnings and
+// events reported to the player. An engine which exits or stops responding
+// is restarted with the same config, the options it was sent are replayed
+// and the command is tried once more
+type engineBase struct {
+	mu       sync.Mutex
+	proto    protocol
+	cfg      *UCIEngine
+	config   Config
+	proc     *engineProcess
+	id       map[string]string
+	options  []EngineOption
+	sent     []Option         // Options sent to the engine, replayed on restart
+	lines    map[int]uci.Info // Lines of the last search by multipv
+	warnings []string         // Problems with the configured options
+	warned   bool             // The warnings were reported
+	event    string           // Last restart, cleared once reported
+}
+
+// start starts the engine process and runs the handshake, which declares
+// the engine's id and options
+func (e *engineBase) start() error {
+	switch strings.ToLower(e.cfg.Protocol) {
+	case "", protocolUCI, protocolCECP, protocolXBoard:
+	default:
+		return fmt.Errorf("engine: unknown protocol %v", e.cfg.Protocol)
+	}
+	return e.proto.handshake()
+}
+
+// probe runs the handshake without configuring the engine
+func (e *engineBase) probe() error {
+	return e.start()
+}
+
+// Start starts the engine now instead of on first use
+func (e *engineBase) Start() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.proc != nil {
+		return nil
+	}
+	return e.launch()
+}
+
+// launch starts the engine process and configures it for the game. The
+// configured options are checked against the ones the engine declares,
+// problems are kept as warnings. The strength limit is applied after them
+// since it depends on the declared options, followed by the options set
+// before the engine started
+func (e *engineBase) launch() error {
+	if err := e.start(); err != nil {
+		if e.proc != nil {
+			e.proc.kill()
+			e.proc = nil
+		}
+		return err
+	}
+	options, warnings := optionCmds(e.cfg, e.config, e.options)
+	e.warnings = warnings
+	options = append(options, strengthOptions(e.options, e.cfg)...)
+	options = append(options, e.sent...)
+	e.sent = nil
+	for _, o := range options {
+		if err := e.setOption(o.Name, o.Value); err != nil {
+			return err
+		}
+	}
+	return e.proto.newGame()
+}
+
+// setOption sends an option to the engine and keeps it for replay
+func (e *engineBase) setOption(name, value string) error {
+	if err := e.proto.sendOption(name, value); err != nil {
+		return err
+	}
+	for i, o := range e.sent {
+		if strings.EqualFold(o.Name, name) {
+			e.sent[i].Value = value
+			return nil
+		}
+	}
+	e.sent = append(e.sent, Option{name, value})
+	return nil
+}
+
+// chess960Mode returns a bool indicating whether the engine was switched to
+// Chess960 mode
+func (e *engineBase) chess960Mode() bool {
+	for _, o := range e.sent {
+		if strings.EqualFold(o.Name, "UCI_Chess960") {
+			return strings.EqualFold(o.Value, "true")
+		}
+	}
+	return false
+}
+
+// SetOption sets an option of the engine. Options set before the engine
+// starts are sent once it does
+func (e *engineBase) SetOption(name, value string) error {
+	e.mu.Lock()
+	if e.proc == nil {
+		e.sent = append(e.sent, Option{name, value})
+		e.mu.Unlock()
+		return nil
+	}
+	e.mu.Unlock()
+	return e.supervise(func() error {
+		if err := e.proto.idle(); err != nil {
+			return err
+		}
+		if err := e.setOption(name, value); err != nil {
+			return err
+		}
+		return e.proto.sync()
+	})
+}
+
+// Press presses a button option of the running engine
+func (e *engineBase) Press(name string) error {
+	return e.supervise(func() error {
+		if err := e.proto.idle(); err != nil {
+			return err
+		}
+		if err := e.proto.press(name); err != nil {
+			return err
+		}
+		return e.proto.sync()
+	})
+}
+
+// NewGame tells the engine that a new game starts
+func (e *engineBase) NewGame() error {
+	return e.supervise(func() error {
+		if err := e.proto.idle(); err != nil {
+			return err
+		}
+		return e.proto.newGame()
+	})
+}
+
+// Lines returns the lines of the last search, one per multipv
+func (e *engineBase) Lines() []uci.Info {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	lines := make([]uci.Info, 0, len(e.lines))
+	for _, info := range e.lines {
+		lines = append(lines, info)
+	}
+	return lines
+}
+
+// ID returns the id the engine declared, i.e., name and author
+func (e *engineBase) ID() map[string]string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	id := make(map[string]string)
+	for k, v := range e.id {
+		id[k] = v
+	}
+	return id
+}
+
+// Options returns the options the engine declared in the handshake. The
+// engine is started if it has not been yet
+func (e *engineBase) Options() ([]EngineOption, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.proc == nil {
+		if err := e.launch(); err != nil {
+			return nil, err
+		}
+	}
+	return append([]EngineOption{}, e.options...), nil
+}
+
+// Warnings returns the problems found with the configured options when the
+// engine was started
+func (e *engineBase) Warnings() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string{}, e.warnings...)
+}
+
+// newWarnings returns the warnings of a started engine which were not
+// reported yet
+func (e *engineBase) newWarnings() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.proc == nil || e.warned {
+		return nil
+	}
+	e.warned = true
+	return append([]string{}, e.warnings...)
+}
+
+// Started returns a bool indicating whether the engine process runs
+func (e *engineBase) Started() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.proc != nil
+}
+
+// Close shuts the engine down
+func (e *engineBase) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.proc != nil {
+		e.proc.quit()
+		e.proc = nil
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package uchess
+
+import (
+	_ "embed" // embeds the opening table
+	"strings"
+	"sync"
+
+	"github.com/notnil/chess"
+)
+
+// The opening table is derived from the lichess chess-openings project
+// (https://github.com/lichess-org/chess-openings) and lists the ECO code,
+// name and position (FEN without move counters or en passant) of each line
+//
+//go:embed openings/eco.tsv
+var ecoData string
+
+// Opening is a named opening from the ECO table
+type Opening struct {
+	ECO  string
+	Name string
+}
+
+var (
+	ecoOnce  sync.Once
+	ecoTable map[string]Opening
+)
+
+// ecoKey returns the key of a position in the opening table. Openings are
+// matched by position so that transpositions are recognized
+func ecoKey(pos *chess.Position) string {
+	fields := strings.Fields(pos.String())
+	if len(fields) < 3 {
+		return ""
+	}
+	return strings.Join(fields[:3], " ")
+}
+
+// loadECO parses the embedded opening table
+func loadECO() {
+	ecoTable = make(map[string]Opening)
+	for i, line := range strings.Split(ecoData, "\n") {
+		fields := strings.Split(line, "\t")
+		// Skip the header and malformed lines
+		if i == 0 || len(fields) != 3 {
+			continue
+		}
+		if _, ok := ecoTable[fields[2]]; !ok {
+			ecoTable[fields[2]] = Opening{fields[0], fields[1]}
+		}
+	}
+}
+
+// FindOpening returns the most specific opening reached in the game. The
+// positions are searched from the latest, so leaving book keeps the name
+// of the last known opening
+func FindOpening(game *chess.Game) (Opening, bool) {
+	ecoOnce.Do(loadECO)
+	positions := game.Positions()
+	for i := len(positions) - 1; i >= 0; i-- {
+		if o, ok := ecoTable[ecoKey(positions[i])]; ok {
+			return o, true
+		}
+	}
+	return Opening{}, false
+}
+
+// String returns the ECO code followed by the opening name
+func (o Opening) String() string {
+	return o.ECO + " " + o.Name
+}
+
+// addOpeningTags sets the ECO and Opening PGN tags when the opening is known
+func addOpeningTags(game *chess.Game) {
+	if o, ok := FindOpening(game); ok {
+		game.AddTagPair("ECO", o.ECO)
+		game.AddTagPair("Opening", o.Name)
+	}
+}
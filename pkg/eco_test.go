@@ -0,0 +1,61 @@
+package uchess
+
+import (
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+func TestFindOpening(t *testing.T) {
+	for _, tc := range []struct {
+		moves []string
+		want  string // ECO code and name, empty when no opening is known
+	}{
+		{nil, ""},
+		{[]string{"e4"}, "B00 King's Pawn"},
+		{[]string{"e4", "e5", "Nf3", "Nc6"}, "C44 King's Knight Opening: Normal Variation"},
+		{[]string{"e4", "e5", "Nf3", "Nc6", "Bb5"}, "C60 Ruy Lopez"},
+		// The longest known line wins
+		{[]string{"e4", "e5", "Nf3", "Nc6", "Bb5", "a6"}, "C70 Ruy Lopez: Morphy Defense"},
+		// Leaving book keeps the last known opening
+		{[]string{"e4", "e5", "Nf3", "Nc6", "Bb5", "h5", "h4", "a5"}, "C60 Ruy Lopez"},
+		// Transpositions are found by position
+		{[]string{"d4", "d5", "Nf3"}, "D02 Queen's Pawn Game: Zukertort Variation"},
+		{[]string{"Nf3", "d5", "d4"}, "D02 Queen's Pawn Game: Zukertort Variation"},
+	} {
+		game := chess.NewGame()
+		for _, m := range tc.moves {
+			if err := game.MoveStr(m); err != nil {
+				t.Fatal(err)
+			}
+		}
+		o, ok := FindOpening(game)
+		if got := o.String(); ok != (tc.want != "") || (ok && got != tc.want) {
+			t.Errorf("FindOpening(%v) = %q, %v, want %q", tc.moves, got, ok, tc.want)
+		}
+	}
+}
+
+func TestECOKey(t *testing.T) {
+	// Positions match without their en passant square and move counters
+	game := chess.NewGame()
+	game.MoveStr("e4")
+	if got, want := ecoKey(game.Position()), e4Key; got != want {
+		t.Errorf("ecoKey() = %q, want %q", got, want)
+	}
+}
+
+func TestAddOpeningTags(t *testing.T) {
+	game := chess.NewGame()
+	addOpeningTags(game)
+	if tag := game.GetTagPair("ECO"); tag != nil {
+		t.Errorf("start position tagged ECO %v, want no tag", tag.Value)
+	}
+	game.MoveStr("e4")
+	game.MoveStr("e5")
+	addOpeningTags(game)
+	eco, opening := game.GetTagPair("ECO"), game.GetTagPair("Opening")
+	if eco == nil || eco.Value != "C20" || opening == nil || opening.Value != "King's Pawn Game" {
+		t.Errorf("tags ECO %v and Opening %v, want C20 King's Pawn Game", eco, opening)
+	}
+}
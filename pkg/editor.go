@@ -0,0 +1,204 @@
+package uchess
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// EditorRows is the number of options the options editor shows at once
+const EditorRows = 12
+
+// OptionsEditor is the overlay for editing the options of a running engine
+type OptionsEditor struct {
+	Side     string            // white, black or hint
+	Eng      Engine            // Engine receiving the options
+	Cfg      *UCIEngine        // Engine config updated along with the engine
+	Options  []EngineOption    // Options declared by the engine
+	Values   map[string]string // Current values by option name
+	Changed  map[string]string // Values set in the editor, saved on request
+	Selected int               // Selected option
+	Offset   int               // First option shown
+	Editing  bool              // A value is being typed
+	Buffer   string            // Value being typed
+	Status   string            // Result of the last action
+	split    func()            // Gives the role an engine of its own, nil when it has one
+}
+
+// optionsCmd opens the options editor for an engine, i.e., options black
+func optionsCmd(gs *GameState, args []string) string {
+	if len(args) != 1 {
+		return "\u26A0 Usage: options <white|black|hint>"
+	}
+	var roleEng *Engine
+	var roleCfg **UCIEngine
+	switch args[0] {
+	case "white":
+		roleEng, roleCfg = &gs.UCI.UciWhite, &gs.UCI.CfgWhite
+	case "black":
+		roleEng, roleCfg = &gs.UCI.UciBlack, &gs.UCI.CfgBlack
+	case "hint":
+		roleEng, roleCfg = &gs.UCI.UciHint, &gs.UCI.CfgHint
+	default:
+		return "\u26A0 Usage: options <white|black|hint>"
+	}
+	eng, cfg := *roleEng, *roleCfg
+	options, err := eng.Options()
+	if err != nil {
+		return "\u26A0 Error. Engine command."
+	}
+	if len(options) == 0 {
+		return "\u26A0 The engine declares no options."
+	}
+
+	e := &OptionsEditor{
+		Side:    args[0],
+		Eng:     eng,
+		Cfg:     cfg,
+		Options: options,
+		Values:  make(map[string]string),
+		Changed: make(map[string]string),
+	}
+	for _, o := range options {
+		e.Values[o.Name] = o.Default
+	}
+	// The values sent when the engine started override the defaults
+	sent, _ := optionCmds(cfg, gs.Config, options)
+	for _, o := range sent {
+		e.Values[o.Name] = o.Value
+	}
+	// Roles sharing the engine process keep their options when this one
+	// changes, so the first change gives the role a config and a process
+	// of its own
+	shared := 0
+	for _, other := range []Engine{gs.UCI.UciWhite, gs.UCI.UciBlack, gs.UCI.UciHint} {
+		if other == eng {
+			shared++
+		}
+	}
+	if shared > 1 {
+		e.split = func() {
+			own := *cfg
+			own.Options = append([]Option{}, cfg.Options...)
+			*roleCfg, *roleEng = &own, NewEngine(&own, gs.Config)
+			e.Cfg, e.Eng = *roleCfg, *roleEng
+		}
+	}
+	gs.Editor = e
+	return strings.Repeat(" ", 80)
+}
+
+// selected returns the selected option
+func (e *OptionsEditor) selected() EngineOption {
+	return e.Options[e.Selected]
+}
+
+// Move changes the selected option by delta, scrolling as needed
+func (e *OptionsEditor) Move(delta int) {
+	e.Selected += delta
+	if e.Selected < 0 {
+		e.Selected = 0
+	} else if e.Selected >= len(e.Options) {
+		e.Selected = len(e.Options) - 1
+	}
+	if e.Selected < e.Offset {
+		e.Offset = e.Selected
+	} else if e.Selected >= e.Offset+EditorRows {
+		e.Offset = e.Selected - EditorRows + 1
+	}
+}
+
+// set validates a value and sends it to the engine
+func (e *OptionsEditor) set(o EngineOption, value string) {
+	if err := o.Validate(value); err != nil {
+		e.Status = "\u26A0 " + err.Error()
+		return
+	}
+	if e.split != nil {
+		e.split()
+		e.split = nil
+	}
+	if err := e.Eng.SetOption(o.Name, value); err != nil {
+		e.Status = "\u26A0 " + err.Error()
+		return
+	}
+	e.Values[o.Name] = value
+	e.Changed[o.Name] = value
+	setOption(e.Cfg, o.Name, value)
+	e.Status = fmt.Sprintf("%v set to %v", o.Name, value)
+}
+
+// Step changes the selected option by one step: spins count up or down,
+// combos cycle through their values and checks toggle
+func (e *OptionsEditor) Step(delta int) {
+	o := e.selected()
+	value := e.Values[o.Name]
+	switch o.Type {
+	case "spin":
+		n, _ := strconv.Atoi(value)
+		e.set(o, strconv.Itoa(n+delta))
+	case "combo":
+		if len(o.Vars) == 0 {
+			return
+		}
+		idx := 0
+		for i, v := range o.Vars {
+			if strings.EqualFold(v, value) {
+				idx = i
+			}
+		}
+		idx = (idx + delta + len(o.Vars)) % len(o.Vars)
+		e.set(o, o.Vars[idx])
+	case "check":
+		e.set(o, strconv.FormatBool(value != "true"))
+	}
+}
+
+// Activate handles enter on the selected option: spins and strings start
+// typing a value, buttons are pressed and the rest step forward
+func (e *OptionsEditor) Activate() {
+	o := e.selected()
+	switch o.Type {
+	case "spin", "string":
+		e.Editing = true
+		e.Buffer = e.Values[o.Name]
+		e.Status = "Type a value, enter to apply, esc to cancel"
+	case "button":
+		if err := e.Eng.Press(o.Name); err != nil {
+			e.Status = "\u26A0 " + err.Error()
+			return
+		}
+		e.Status = o.Name + " pressed"
+	default:
+		e.Step(1)
+	}
+}
+
+// Save writes the values set in the editor to the config file
+func (e *OptionsEditor) Save(config Config) {
+	if config.File == "" {
+		e.Status = "\u26A0 No config file. Start uchess with -cfg to save."
+		return
+	}
+	if len(e.Changed) == 0 {
+		e.Status = "Nothing to save."
+		return
+	}
+	if err := SaveEngineOptions(config.File, e.Cfg.Name, e.Changed); err != nil {
+		e.Status = "\u26A0 " + err.Error()
+		return
+	}
+	e.Status = "Saved to " + config.File
+}
+
+// Apply ends typing and sets the typed value
+func (e *OptionsEditor) Apply() {
+	e.Editing = false
+	e.set(e.selected(), strings.TrimSpace(e.Buffer))
+}
+
+// Cancel ends typing and leaves the value as it was
+func (e *OptionsEditor) Cancel() {
+	e.Editing = false
+	e.Status = ""
+}
@@ -1,10 +1,9 @@
 package uchess
 
 import (
-	"fmt"
+	"strconv"
+	"strings"
 	"time"
-
-	"github.com/notnil/chess/uci"
 )
 
 // Option allows arbitrary UCI options to be sent
@@ -15,71 +14,150 @@ type Option struct {
 
 // UCIEngine defines a UCIEngine configuration
 type UCIEngine struct {
-	Name        string        `json:"name"`
-	Path        string        `json:"engine"`
-	Hash        int           `json:"hash"`
-	Ponder      bool          `json:"ponder"`
-	OwnBook     bool          `json:"ownBook"`
-	MultiPV     int           `json:"multiPV"`
-	Depth       int           `json:"depth"`
-	SearchMoves string        `json:"searchMoves"`
-	MoveTime    time.Duration `json:"moveTime"`
-	Options     []Option      `json:"options"`
+	Name          string            `json:"name"`
+	Path          string            `json:"engine"`
+	Hash          int               `json:"hash"`
+	Ponder        bool              `json:"ponder"`
+	OwnBook       bool              `json:"ownBook"`
+	MultiPV       int               `json:"multiPV"`
+	Depth         int               `json:"depth"`
+	SearchMoves   string            `json:"searchMoves"`
+	MoveTime      time.Duration     `json:"moveTime"`
+	Options       []Option          `json:"options"`
+	Book          string            `json:"book"`
+	BookDepth     int               `json:"bookDepth"`
+	BookSelect    string            `json:"bookSelect"`
+	LimitStrength bool              `json:"limitStrength"`
+	Elo           int               `json:"elo"`
+	Protocol      string            `json:"protocol"`
+	Args          []string          `json:"args"`
+	Env           map[string]string `json:"env"`
+	WorkDir       string            `json:"workDir"`
+}
+
+// setOption sets an option of an engine config. Options with a config key
+// of their own set the key, others are added to the custom options when the
+// config does not list them
+func setOption(cfg *UCIEngine, name, value string) {
+	switch strings.ToLower(name) {
+	case "hash":
+		cfg.Hash, _ = strconv.Atoi(value)
+		return
+	case "ponder":
+		cfg.Ponder, _ = strconv.ParseBool(value)
+		return
+	case "ownbook":
+		cfg.OwnBook, _ = strconv.ParseBool(value)
+		return
+	case "multipv":
+		cfg.MultiPV, _ = strconv.Atoi(value)
+		return
+	}
+	for i, opt := range cfg.Options {
+		if strings.EqualFold(opt.Name, name) {
+			cfg.Options[i].Value = value
+			return
+		}
+	}
+	cfg.Options = append(cfg.Options, Option{name, value})
 }
 
 // UCIState holds the UCI engine state
 type UCIState struct {
-	UciWhite *uci.Engine // UCI Engine
-	UciBlack *uci.Engine // UCI Engine
-	UciHint  *uci.Engine // UCI Engine
-	CfgWhite *UCIEngine  // UCI Engine Config
-	CfgBlack *UCIEngine  // UCI Engine Config
-	CfgHint  *UCIEngine  // UCI Engine Config
+	UciWhite  Engine     // UCI Engine
+	UciBlack  Engine     // UCI Engine
+	UciHint   Engine     // UCI Engine
+	CfgWhite  *UCIEngine // UCI Engine Config
+	CfgBlack  *UCIEngine // UCI Engine Config
+	CfgHint   *UCIEngine // UCI Engine Config
+	BookWhite *Book      // Opening Book
+	BookBlack *Book      // Opening Book
 }
 
-// cfgEngines configures the UCI engine
-func cfgEngines(eng *uci.Engine, cfg *UCIEngine) {
-	optHash := uci.CmdSetOption{Name: "hash", Value: fmt.Sprintf("%v", cfg.Hash)}
-	optPonder := uci.CmdSetOption{Name: "ponder", Value: fmt.Sprintf("%v", cfg.Ponder)}
-	optOwnBook := uci.CmdSetOption{Name: "ownbook", Value: fmt.Sprintf("%v", cfg.OwnBook)}
-	optMultiPV := uci.CmdSetOption{Name: "multipv", Value: fmt.Sprintf("%v", cfg.MultiPV)}
-	uciArgs := []uci.Cmd{uci.CmdUCI, uci.CmdIsReady, optHash, optPonder, optOwnBook, optMultiPV, uci.CmdUCINewGame}
-	// Send any custom options that were specified
-	for _, option := range cfg.Options {
-		uciArgs = append(uciArgs, uci.CmdSetOption{Name: option.Name, Value: option.Value})
-	}
-
-	if err := eng.Run(uciArgs...); err != nil {
+// InitEngine starts and configures a single UCI engine. The tablebase
+// path, when set, is passed along so that the engine probes the same tables
+// as uchess and Chess960 games switch the engine into Chess960 mode
+func InitEngine(cfg *UCIEngine, config Config) Engine {
+	eng, err := StartEngine(cfg, config)
+	if err != nil {
 		panic(err)
 	}
+	return eng
 }
 
-// InitEngines configures UCI engines if the config dictates that
-// they are required. The white and black engines are returned
-// respectively
-func InitEngines(config Config) (*uci.Engine, *uci.Engine, *uci.Engine) {
-	cfgWhite, cfgBlack, cfgHint := ImportEngines(config.UCIWhite, config.UCIBlack, config.UCIHint, config.UCIEngines)
-	var engWhite, engBlack, engHint *uci.Engine
-
-	engWhite, err := uci.New(cfgWhite.Path)
+// InitBook loads the opening book for an engine config. If the config
+// does not specify a book, nil is returned
+func InitBook(cfg *UCIEngine) *Book {
+	if cfg.Book == "" {
+		return nil
+	}
+	book, err := LoadBook(cfg.Book)
 	if err != nil {
 		panic(err)
 	}
-	cfgEngines(engWhite, cfgWhite)
+	return book
+}
 
-	engBlack, err = uci.New(cfgBlack.Path)
-	if err != nil {
-		panic(err)
+// shareable returns a bool indicating whether two roles can share one
+// engine process, which is the case when they use the same engine at full
+// strength with the same options. The adaptive level tunes the CPU engine,
+// so it is kept apart
+func shareable(a, b *UCIEngine, config Config) bool {
+	return a.Name == b.Name && !a.LimitStrength && !b.LimitStrength && !config.Adaptive && sameOptions(a, b)
+}
+
+// sameOptions returns a bool indicating whether two engine configs send
+// the same options to the engine
+func sameOptions(a, b *UCIEngine) bool {
+	if a.Hash != b.Hash || a.Ponder != b.Ponder || a.OwnBook != b.OwnBook || a.MultiPV != b.MultiPV {
+		return false
+	}
+	if len(a.Options) != len(b.Options) {
+		return false
 	}
-	cfgEngines(engBlack, cfgBlack)
+	for _, o := range a.Options {
+		found := false
+		for _, p := range b.Options {
+			if strings.EqualFold(o.Name, p.Name) && o.Value == p.Value {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
 
-	engHint, err = uci.New(cfgHint.Path)
-	if err != nil {
-		panic(err)
+// InitEngines returns the white, black and hint engines. Roles using the
+// same engine share its process, i.e., the hint engine is idle while the
+// CPU thinks. The engines start when they are first used, so human vs
+// human games do not start any
+func InitEngines(config Config, cfgWhite, cfgBlack, cfgHint *UCIEngine) (Engine, Engine, Engine) {
+	// Hints come from the engine at full strength
+	cfgHint.LimitStrength = false
+	white := NewEngine(cfgWhite, config)
+	black := white
+	if !shareable(cfgWhite, cfgBlack, config) {
+		black = NewEngine(cfgBlack, config)
+	}
+	hint := NewEngine(cfgHint, config)
+	if shareable(cfgBlack, cfgHint, config) {
+		hint = black
+	} else if shareable(cfgWhite, cfgHint, config) {
+		hint = white
 	}
-	cfgEngines(engHint, cfgHint)
+	return white, black, hint
+}
 
-	return engWhite, engBlack, engHint
+// FindEngine returns the engine config called name
+func FindEngine(name string, engines []UCIEngine) (*UCIEngine, bool) {
+	for _, e := range engines {
+		if e.Name == name {
+			return &e, true
+		}
+	}
+	return nil, false
 }
 
 // ImportEngines returns a UCIEngine config for white and black
@@ -116,5 +194,10 @@ func ImportEngines(uciWhite string, uciBlack string, uciHint string, engines []U
 		panic("failed to import hint engine config")
 	}
 
+	// Each role gets its own options, which the options editor changes
+	cfgWhite.Options = append([]Option{}, cfgWhite.Options...)
+	cfgBlack.Options = append([]Option{}, cfgBlack.Options...)
+	cfgHint.Options = append([]Option{}, cfgHint.Options...)
+
 	return &cfgWhite, &cfgBlack, &cfgHint
 }
@@ -0,0 +1,301 @@
+package uchess
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/uci"
+)
+
+func TestInitEnginesSharing(t *testing.T) {
+	cfg := mockEngine(t, "mock", "")
+	other := mockEngine(t, "other", "")
+	limited := *cfg
+	limited.LimitStrength, limited.Elo = true, 1500
+	skilled := *cfg
+	skilled.Options = []Option{{"Skill Level", "20"}}
+
+	for _, tc := range []struct {
+		name               string
+		config             Config
+		white, black, hint *UCIEngine
+		blackShared        bool // Black uses the white engine
+		hintShared         bool // Hint uses the white or black engine
+	}{
+		{"same engine", testConfig("human", "cpu"), cfg, cfg, cfg, true, true},
+		{"other engine", testConfig("human", "cpu"), cfg, other, other, false, true},
+		{"other hint", testConfig("human", "cpu"), cfg, cfg, other, true, false},
+		{"limited black", testConfig("human", "cpu"), cfg, &limited, cfg, false, true},
+		{"other options", testConfig("human", "cpu"), cfg, cfg, &skilled, true, false},
+		{"adaptive", Config{Adaptive: true, WhitePiece: "human", BlackPiece: "cpu"}, cfg, cfg, cfg, false, false},
+	} {
+		white, black, hint := InitEngines(tc.config, tc.white, tc.black, tc.hint)
+		if shared := black == white; shared != tc.blackShared {
+			t.Errorf("%v: black shares white = %v, want %v", tc.name, shared, tc.blackShared)
+		}
+		if shared := hint == white || hint == black; shared != tc.hintShared {
+			t.Errorf("%v: hint shared = %v, want %v", tc.name, shared, tc.hintShared)
+		}
+		// Engines start on first use
+		for _, eng := range []Engine{white, black, hint} {
+			if eng.Started() {
+				t.Errorf("%v: engine started before use", tc.name)
+			}
+		}
+	}
+}
+
+func TestInitEnginesHintFullStrength(t *testing.T) {
+	cfg := mockEngine(t, "mock", "")
+	hintCfg := *cfg
+	hintCfg.LimitStrength = true
+	InitEngines(testConfig("human", "cpu"), cfg, cfg, &hintCfg)
+	if hintCfg.LimitStrength {
+		t.Error("the hint engine plays at a limited strength")
+	}
+}
+
+func TestInitEnginesLazyStart(t *testing.T) {
+	cfg := mockEngine(t, "mock", "")
+	gs := newTestState(t, testConfig("human", "cpu"), cfg, cfg, cfg)
+	if gs.UCI.UciBlack.Started() {
+		t.Fatal("engine started before use")
+	}
+	if _, err := gs.UCI.UciBlack.Search(gs.Game.Position(), uci.CmdGo{Depth: 1}); err != nil {
+		t.Fatal(err)
+	}
+	// All roles share the process started by the search
+	for _, eng := range []Engine{gs.UCI.UciWhite, gs.UCI.UciBlack, gs.UCI.UciHint} {
+		if !eng.Started() {
+			t.Error("shared engine not started by the search")
+		}
+	}
+}
+
+func TestEngineOptionWarnings(t *testing.T) {
+	cfg := mockEngine(t, "mock", "option name Hash type spin default 16 min 1 max 1024\n")
+	cfg.Hash = 4096
+	cfg.Options = []Option{{"Nope", "1"}}
+	eng, err := StartEngine(cfg, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer eng.Close()
+	warnings := strings.Join(eng.Warnings(), "\n")
+	for _, want := range []string{"Hash is above the maximum of 1024", "unknown option Nope"} {
+		if !strings.Contains(warnings, want) {
+			t.Errorf("warnings %q do not report %q", warnings, want)
+		}
+	}
+}
+
+func TestProbeEngine(t *testing.T) {
+	cfg := mockEngine(t, "mock", "id name Mock\noption name Threads type spin default 1 min 1 max 8\noption name Hash type spin default 16 min 1 max 1024\n")
+	info, err := ProbeEngine(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ID["name"] != "Mock" {
+		t.Errorf("id %v, want the name Mock", info.ID)
+	}
+	if len(info.Options) != 2 || info.Options[0].Name != "Hash" {
+		t.Errorf("options %v, want Hash and Threads in order", info.Options)
+	}
+}
+
+func TestParseOptionComboWithoutVars(t *testing.T) {
+	if o, err := ParseOption("option name Style type combo default Normal"); err == nil {
+		t.Errorf("ParseOption() = %+v, want an error for a combo without var", o)
+	}
+	if o, err := parseCECPOption("Style -combo"); err == nil {
+		t.Errorf("parseCECPOption() = %+v, want an error for a combo without values", o)
+	}
+	// Editors of options from elsewhere leave such combos alone
+	e := &OptionsEditor{Options: []EngineOption{{Name: "Style", Type: "combo"}}, Values: map[string]string{}}
+	e.Step(1)
+}
+
+func TestOptionCmdsSyzygyPath(t *testing.T) {
+	withPath := []EngineOption{{Name: "SyzygyPath", Type: "string", Default: "<empty>"}}
+	for _, tc := range []struct {
+		path     string
+		declared []EngineOption
+		cmds     int
+		warnings int
+	}{
+		{"", withPath, 0, 0},
+		{"/tb/wdl:/tb/dtz", withPath, 1, 0},
+		// Engines without tablebase support are warned about
+		{"/tb", nil, 0, 1},
+	} {
+		cfg := &UCIEngine{Name: "mock"}
+		cmds, warnings := optionCmds(cfg, Config{SyzygyPath: tc.path}, tc.declared)
+		if len(cmds) != tc.cmds || len(warnings) != tc.warnings || (tc.cmds == 1 && cmds[0] != Option{"SyzygyPath", tc.path}) {
+			t.Errorf("path %q: optionCmds() = %v, %v, want %v options and %v warnings", tc.path, cmds, warnings, tc.cmds, tc.warnings)
+		}
+	}
+}
+
+func TestEngineOptionsStartError(t *testing.T) {
+	cfg := &UCIEngine{Name: "none", Path: "uchess-no-engine"}
+	if _, err := ProbeEngine(cfg); err == nil {
+		t.Error("ProbeEngine() succeeded without an engine")
+	}
+	eng := NewEngine(cfg, Config{})
+	defer eng.Close()
+	if _, err := eng.Options(); err == nil {
+		t.Error("Options() succeeded without an engine")
+	}
+}
+
+func TestOptionsEditorSplitsSharedEngine(t *testing.T) {
+	cfg := mockEngine(t, "mock", "option name Skill Level type spin default 3 min 0 max 20\n")
+	gs := newTestState(t, testConfig("human", "cpu"), cfg, cfg, cfg)
+	black := gs.UCI.UciBlack
+	optionsCmd(gs, []string{"hint"})
+	if gs.Editor == nil {
+		t.Fatal("options editor not opened")
+	}
+	gs.Editor.Step(1)
+	if gs.Editor.Status != "Skill Level set to 4" {
+		t.Fatalf("status %q, want the skill level set", gs.Editor.Status)
+	}
+	// The hint gets its own engine, the CPU keeps its strength
+	if gs.UCI.UciHint == black || gs.Editor.Eng != gs.UCI.UciHint {
+		t.Error("hint still shares the CPU engine")
+	}
+	if gs.UCI.UciBlack != black || gs.UCI.UciWhite != black {
+		t.Error("the other roles lost their engine")
+	}
+	if len(cfg.Options) != 0 {
+		t.Errorf("shared config changed to %v", cfg.Options)
+	}
+	if opts := gs.UCI.CfgHint.Options; len(opts) != 1 || opts[0].Value != "4" {
+		t.Errorf("hint options %v, want Skill Level 4", opts)
+	}
+	// Later changes go to the same engine
+	hint := gs.UCI.UciHint
+	gs.Editor.Step(1)
+	if gs.UCI.UciHint != hint {
+		t.Error("second change split the engine again")
+	}
+}
+
+// mockHeader declares the options of the restart tests
+const mockHeader = "id name Mock\noption name Hash type spin default 16 min 1 max 1024\noption name Style type combo default Normal var Normal var Risky\n"
+
+// starts splits a command log into the commands read by each start
+func starts(t *testing.T, log string) []string {
+	t.Helper()
+	data, err := ioutil.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runs := strings.Split("\n"+string(data), "\nuci\n")
+	return runs[1:]
+}
+
+func TestEngineRestartReplaysOptions(t *testing.T) {
+	cfg := mockEngine(t, "mock", mockHeader+"position *\ncrash 3\n")
+	log := mockRestart(t, cfg, mockHeader+"position *\nbestmove e2e4\n")
+	cfg.Hash = 64
+	eng, err := StartEngine(cfg, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer eng.Close()
+	if err := eng.SetOption("Style", "Risky"); err != nil {
+		t.Fatal(err)
+	}
+	res, err := eng.Search(chess.StartingPosition(), uci.CmdGo{Depth: 1})
+	if err != nil || res.BestMove == nil || res.BestMove.String() != "e2e4" {
+		t.Fatalf("Search() = %v, %v, want e2e4 from the restarted engine", res.BestMove, err)
+	}
+	if event := eng.Event(); event != "mock crashed and was restarted" {
+		t.Errorf("Event() = %q, want the restart", event)
+	}
+	runs := starts(t, log)
+	if len(runs) != 2 {
+		t.Fatalf("engine started %v times, want 2", len(runs))
+	}
+	// The options reach the new process before the search
+	for _, want := range []string{"setoption name Hash value 64", "setoption name Style value Risky"} {
+		i := strings.Index(runs[1], want)
+		if i < 0 || i > strings.Index(runs[1], "\ngo ") {
+			t.Errorf("restarted engine read %q, want %q before the search", runs[1], want)
+		}
+	}
+}
+
+func TestEngineRestartFailsAgain(t *testing.T) {
+	cfg := mockEngine(t, "mock", mockHeader+"position *\ncrash 3\n")
+	log := mockRestart(t, cfg, mockHeader+"position *\ncrash 4\n")
+	eng, err := StartEngine(cfg, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer eng.Close()
+	if _, err := eng.Search(chess.StartingPosition(), uci.CmdGo{Depth: 1}); err == nil {
+		t.Fatal("Search() succeeded, want the second crash")
+	}
+	// The second failure is returned without another restart
+	if runs := starts(t, log); len(runs) != 2 {
+		t.Errorf("engine started %v times, want 2", len(runs))
+	}
+	if event := eng.Event(); event != "mock crashed and was restarted" {
+		t.Errorf("Event() = %q, want one restart", event)
+	}
+}
+
+// TestEngineReplay plays testdata/session.log with uciproxy replay as the
+// engine. The session was recorded through uciproxy from mockuci answering
+// like Stockfish
+func TestEngineReplay(t *testing.T) {
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("no go tool to build uciproxy")
+	}
+	proxy := filepath.Join(t.TempDir(), "uciproxy")
+	build := exec.Command(goTool, "build", "-o", proxy, "../cmd/uciproxy")
+	// The build cache is in the home directory the tests started with
+	build.Env = append(os.Environ(), "HOME="+userHome)
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("building uciproxy: %v\n%s", err, out)
+	}
+	session, err := filepath.Abs(filepath.Join("testdata", "session.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &UCIEngine{Name: "stockfish", Path: proxy, Args: []string{"replay", session}, Depth: 8}
+	eng, err := StartEngine(cfg, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer eng.Close()
+	if name := eng.ID()["name"]; name != "Stockfish 14.1" {
+		t.Errorf("id name %q, want Stockfish 14.1", name)
+	}
+
+	game := chess.NewGame()
+	res, err := eng.Search(game.Position(), uci.CmdGo{Depth: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.BestMove == nil || res.BestMove.String() != "e2e4" || res.Info.Depth != 8 || res.Info.Score.CP != 41 {
+		t.Errorf("search = %v %+v, want e2e4 at depth 8 scoring 41", res.BestMove, res.Info)
+	}
+	// The repeated go command is answered by its second recording
+	game.MoveStr("e4")
+	res, err = eng.Search(game.Position(), uci.CmdGo{Depth: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.BestMove == nil || res.BestMove.String() != "e7e5" {
+		t.Errorf("search = %v, want e7e5", res.BestMove)
+	}
+}
@@ -41,6 +41,26 @@ func setWhitePieceName(c *Config) {
 	}
 }
 
+// LoadConfig reads the config file when one is provided and otherwise
+// falls back to the zero configuration defaults
+func LoadConfig(file string) Config {
+	// If UCI engine is specified in config, it is taken at face value
+	if file != "" {
+		return ReadConfig(file)
+	}
+
+	// Zero configuration config (hopefully)
+	config := MakeDefault()
+	config.Themes = ReadThemes()
+	uciPath := config.UCIEngines[0].Path
+
+	// If the UCI engine cannot be found, prompt to install if applicable
+	if !IsFile(uciPath) {
+		FetchStockfish()
+	}
+	return config
+}
+
 // Init sets up the app config
 func Init() Config {
 	var config Config
@@ -49,6 +69,8 @@ func Init() Config {
 	white := flag.String("white", "human", "white piece input")
 	black := flag.String("black", "cpu", "black piece input")
 	themes := flag.Bool("themes", false, "list theme names and exit")
+	chess960 := flag.String("chess960", "", "Chess960 start position (0-959 or random)")
+	resume := flag.Bool("resume", false, "resume the unfinished game without asking")
 
 	flag.Parse()
 
@@ -72,27 +94,27 @@ func Init() Config {
 		os.Exit(0)
 	}
 
+	// Report invalid positions the way flag reports invalid values
+	if _, err := ParseChess960(*chess960); *chess960 != "" && err != nil {
+		fmt.Fprintf(flag.CommandLine.Output(), "invalid value %q for flag -chess960: %v\n", *chess960, err)
+		flag.Usage()
+		os.Exit(2)
+	}
+
 	// Load config file if provided
 	// Override command line flags for black/white
-	// If UCI engine is specified in config, it is taken at face value
+	config = LoadConfig(*cfg)
 	if *cfg != "" {
-		config = ReadConfig(*cfg)
 		*white = config.WhitePiece
 		*black = config.BlackPiece
-	} else {
-		// Zero configuration config (hopefully)
-		config = MakeDefault()
-		config.Themes = ReadThemes()
-		uciPath := config.UCIEngines[0].Path
-
-		// If the UCI engine cannot be found, prompt to install if applicable
-		if !IsFile(uciPath) {
-			FetchStockfish()
-		}
 	}
 
 	config.WhitePiece = *white
 	config.BlackPiece = *black
+	if *chess960 != "" {
+		config.Chess960 = *chess960
+	}
+	config.Resume = *resume
 
 	if config.WhiteName == "" {
 		setWhitePieceName(&config)
@@ -0,0 +1,188 @@
+package uchess
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+// journalFile stores the game in progress in the app dir
+const journalFile = "journal.json"
+
+// Journal is the autosaved state of the game in progress. It is written
+// after every move so that the game survives a crash or a closed terminal
+type Journal struct {
+	Saved       time.Time            `json:"saved"`
+	StartFEN    string               `json:"startFEN"`
+	Chess960    bool                 `json:"chess960"`
+	Moves       []string             `json:"moves"` // UCI notation
+	BookPlies   []int                `json:"bookPlies"`
+	Annotations map[int][]Annotation `json:"annotations"`
+	WhitePiece  string               `json:"whitePiece"`
+	BlackPiece  string               `json:"blackPiece"`
+	WhiteName   string               `json:"whiteName"`
+	BlackName   string               `json:"blackName"`
+	UCIWhite    string               `json:"uciWhite"`
+	UCIBlack    string               `json:"uciBlack"`
+	UCIHint     string               `json:"uciHint"`
+}
+
+// journalPath returns the location of the journal
+func journalPath() string {
+	return filepath.Join(AppDir(), journalFile)
+}
+
+// SaveJournal writes the game in progress to the app dir. Finished games
+// and games without moves clear the journal instead. Puzzles and games
+// under review are not journaled, so the unfinished game stays resumable
+func SaveJournal(gs *GameState) error {
+	if gs.Puzzle != nil || gs.Reviewing {
+		return nil
+	}
+	moves := gs.Game.Moves()
+	if gs.Game.Outcome() != chess.NoOutcome || len(moves) == 0 {
+		return ClearJournal()
+	}
+
+	j := Journal{
+		Saved:       time.Now(),
+		StartFEN:    gs.StartFEN,
+		Chess960:    IsChess960(gs.Game),
+		Moves:       make([]string, 0, len(moves)),
+		BookPlies:   make([]int, 0, len(gs.BookPlies)),
+		Annotations: gs.Annotations,
+		WhitePiece:  gs.Config.WhitePiece,
+		BlackPiece:  gs.Config.BlackPiece,
+		WhiteName:   gs.Config.WhiteName,
+		BlackName:   gs.Config.BlackName,
+		UCIWhite:    gs.Config.UCIWhite,
+		UCIBlack:    gs.Config.UCIBlack,
+		UCIHint:     gs.Config.UCIHint,
+	}
+	for _, move := range moves {
+		j.Moves = append(j.Moves, move.String())
+	}
+	for ply := range gs.BookPlies {
+		j.BookPlies = append(j.BookPlies, ply)
+	}
+
+	if err := os.MkdirAll(AppDir(), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(&j, "", "    ")
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file first so a crash never leaves half a journal
+	tmp := journalPath() + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, journalPath())
+}
+
+// LoadJournal reads the journal from the app dir
+func LoadJournal() (*Journal, error) {
+	data, err := ioutil.ReadFile(journalPath())
+	if err != nil {
+		return nil, err
+	}
+	var j Journal
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// ClearJournal removes the journal from the app dir
+func ClearJournal() error {
+	if err := os.Remove(journalPath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// OfferResume returns the journaled game when the user wants to resume it.
+// The user is asked unless the resume flag was given. A declined game is
+// discarded
+func OfferResume(config Config) *Journal {
+	j, err := LoadJournal()
+	if err != nil || len(j.Moves) == 0 {
+		return nil
+	}
+	if config.Resume {
+		return j
+	}
+
+	var resp string
+	for resp != "yes" && resp != "no" && resp != "y" && resp != "n" {
+		fmt.Printf("Resume the unfinished game from %v (%v plies)? [y/n] ",
+			j.Saved.Format("2006-01-02 15:04"), len(j.Moves))
+		reader := bufio.NewReader(os.Stdin)
+		resp, err = reader.ReadString('\n')
+		resp = strings.TrimSpace(strings.ToLower(resp))
+		// Treat a closed stdin as a no
+		if err != nil && resp == "" {
+			resp = "no"
+		}
+	}
+	if resp == "no" || resp == "n" {
+		ClearJournal()
+		return nil
+	}
+	return j
+}
+
+// Apply restores the player config of the journaled game. The engines are
+// only restored while the config still defines them
+func (j *Journal) Apply(config *Config) {
+	config.WhitePiece = j.WhitePiece
+	config.BlackPiece = j.BlackPiece
+	config.WhiteName = j.WhiteName
+	config.BlackName = j.BlackName
+	_, whiteFound := FindEngine(j.UCIWhite, config.UCIEngines)
+	_, blackFound := FindEngine(j.UCIBlack, config.UCIEngines)
+	_, hintFound := FindEngine(j.UCIHint, config.UCIEngines)
+	if whiteFound && blackFound && hintFound {
+		config.UCIWhite = j.UCIWhite
+		config.UCIBlack = j.UCIBlack
+		config.UCIHint = j.UCIHint
+	}
+	// The engines need to know about Chess960 castling
+	if !j.Chess960 {
+		config.Chess960 = ""
+	} else if config.Chess960 == "" {
+		config.Chess960 = chess960Random
+	}
+}
+
+// ResumeGame replays the journaled game onto the game state
+func ResumeGame(gs *GameState, j *Journal) error {
+	game, err := NewGameFEN(j.StartFEN, j.Chess960)
+	if err != nil {
+		return err
+	}
+	for _, s := range j.Moves {
+		move := uciMove(game.Position(), s)
+		if move == nil || game.Move(move) != nil {
+			return errors.New("journal: invalid move " + s)
+		}
+	}
+	StartGame(gs, game)
+	gs.Annotations = j.Annotations
+	if len(j.BookPlies) > 0 {
+		gs.BookPlies = make(map[int]bool)
+		for _, ply := range j.BookPlies {
+			gs.BookPlies[ply] = true
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package uchess
+
+import (
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+func TestSaveJournal(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		setup func(gs *GameState)
+		moves []string
+		want  int // Plies in the journal afterwards, -1 for no journal
+	}{
+		{"game in progress", func(*GameState) {}, []string{"e4", "e5"}, 2},
+		{"game without moves", func(*GameState) {}, nil, -1},
+		{"finished game", func(*GameState) {}, []string{"f3", "e5", "g4", "Qh4#"}, -1},
+		// The journal of the unfinished game is kept
+		{"puzzle", func(gs *GameState) { gs.Puzzle = &PuzzleState{} }, []string{"d4"}, 3},
+		{"review", func(gs *GameState) { gs.Reviewing = true }, []string{"d4"}, 3},
+		{"finished review", func(gs *GameState) { gs.Reviewing = true }, []string{"f3", "e5", "g4", "Qh4#"}, 3},
+	} {
+		// A journal of an unfinished game from an earlier session
+		earlier := &GameState{Game: chess.NewGame()}
+		for _, m := range []string{"c4", "c5", "Nc3"} {
+			earlier.Game.MoveStr(m)
+		}
+		if err := SaveJournal(earlier); err != nil {
+			t.Fatal(err)
+		}
+
+		gs := &GameState{Game: chess.NewGame(), Config: testConfig("human", "cpu")}
+		tc.setup(gs)
+		for _, m := range tc.moves {
+			if err := gs.Game.MoveStr(m); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := SaveJournal(gs); err != nil {
+			t.Fatal(err)
+		}
+		if RecordGame(gs); tc.want == 3 && gs.Recorded {
+			t.Errorf("%v: game recorded, want it left out of the store", tc.name)
+		}
+		j, err := LoadJournal()
+		switch {
+		case tc.want < 0 && err == nil:
+			t.Errorf("%v: journal with %v plies, want none", tc.name, len(j.Moves))
+		case tc.want >= 0 && (err != nil || len(j.Moves) != tc.want):
+			t.Errorf("%v: journal %+v, %v, want %v plies", tc.name, j, err, tc.want)
+		}
+	}
+	ClearJournal()
+}
+
+func TestResumeGame(t *testing.T) {
+	fen := "4k3/8/8/8/8/8/8/4K2R w K - 0 1"
+	gs := &GameState{Config: testConfig("human", "cpu")}
+	ProcessCmd("newgame "+fen, gs)
+	gs.Game.MoveStr("O-O")
+	gs.Game.MoveStr("Kd7")
+	gs.BookPlies = map[int]bool{1: true}
+	if err := SaveJournal(gs); err != nil {
+		t.Fatal(err)
+	}
+	defer ClearJournal()
+
+	j, err := LoadJournal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resumed := &GameState{Config: testConfig("human", "human")}
+	j.Apply(&resumed.Config)
+	if err := ResumeGame(resumed, j); err != nil {
+		t.Fatal(err)
+	}
+	if resumed.StartFEN != fen || resumed.Game.Position().String() != gs.Game.Position().String() || !resumed.BookPlies[1] {
+		t.Errorf("resumed at %v from %v, want %v from %v", resumed.Game.Position(), resumed.StartFEN, gs.Game.Position(), fen)
+	}
+	if resumed.Config.BlackPiece != "cpu" {
+		t.Errorf("resumed black %v, want cpu", resumed.Config.BlackPiece)
+	}
+}
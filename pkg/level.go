@@ -0,0 +1,181 @@
+package uchess
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+const (
+	// levelFile stores the adaptive difficulty level in the app dir
+	levelFile = "level.json"
+	// defaultLevel matches the skill level of the default engine config
+	defaultLevel = 3
+	// maxLevel is the strongest level, which is the top Stockfish skill
+	maxLevel = 20
+	// levelHistory is the number of games shown by the level command
+	levelHistory = 10
+	// skillOption is the engine option set by the level
+	skillOption = "Skill Level"
+)
+
+// LevelGame is a finished game played in adaptive mode
+type LevelGame struct {
+	Date   string `json:"date"`
+	Level  int    `json:"level"`
+	Result string `json:"result"` // win, loss or draw for the human
+}
+
+// LevelStats holds the adaptive difficulty level across sessions
+type LevelStats struct {
+	Level   int         `json:"level"`
+	History []LevelGame `json:"history"`
+}
+
+// levelPath returns the location of the level stats
+func levelPath() string {
+	return filepath.Join(AppDir(), levelFile)
+}
+
+// LoadLevelStats reads the level stats from the app dir, new players start
+// at the default level
+func LoadLevelStats() LevelStats {
+	stats := LevelStats{Level: defaultLevel}
+	if data, err := ioutil.ReadFile(levelPath()); err == nil {
+		json.Unmarshal(data, &stats)
+	}
+	return stats
+}
+
+// saveLevelStats writes the level stats to the app dir
+func saveLevelStats(stats LevelStats) error {
+	if err := os.MkdirAll(AppDir(), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(&stats, "", "    ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(levelPath(), data, 0644)
+}
+
+// clampLevel keeps a level within the supported range
+func clampLevel(level int) int {
+	if level < 0 {
+		return 0
+	} else if level > maxLevel {
+		return maxLevel
+	}
+	return level
+}
+
+// levelSettings returns the search depth, move time in ms and skill level
+// of a level. Each level searches a little deeper and longer
+func levelSettings(level int) (int, time.Duration, int) {
+	return 1 + level/2, time.Duration(50 + 50*level), level
+}
+
+// cpuOpponent returns the engine and config of the CPU opponent when a
+// human plays the CPU
+func cpuOpponent(gs *GameState) (Engine, *UCIEngine, chess.Color, bool) {
+	whiteCPU := IsCPU(chess.White, gs.Config)
+	blackCPU := IsCPU(chess.Black, gs.Config)
+	switch {
+	case whiteCPU && !blackCPU:
+		return gs.UCI.UciWhite, gs.UCI.CfgWhite, chess.Black, true
+	case blackCPU && !whiteCPU:
+		return gs.UCI.UciBlack, gs.UCI.CfgBlack, chess.White, true
+	}
+	return nil, nil, chess.NoColor, false
+}
+
+// ApplyLevel sets the CPU opponent's strength to the level in adaptive
+// mode. The skill option is sent to the running engine
+func ApplyLevel(gs *GameState, level int) {
+	eng, cfg, _, ok := cpuOpponent(gs)
+	if !gs.Config.Adaptive || !ok {
+		return
+	}
+	// Copy the options so the engine list in the config is left alone
+	cfg.Options = append([]Option{}, cfg.Options...)
+	depth, moveTime, skill := levelSettings(level)
+	cfg.Depth, cfg.MoveTime = depth, moveTime
+	setOption(cfg, skillOption, strconv.Itoa(skill))
+	eng.SetOption(skillOption, strconv.Itoa(skill))
+}
+
+// humanResult returns win, loss or draw for the human playing color
+func humanResult(outcome chess.Outcome, color chess.Color) string {
+	switch {
+	case outcome == chess.Draw:
+		return "draw"
+	case outcome == chess.WhiteWon && color == chess.White,
+		outcome == chess.BlackWon && color == chess.Black:
+		return "win"
+	}
+	return "loss"
+}
+
+// adaptLevel moves the level after a finished game, up after a win and down
+// after a loss. Stepping one level per game converges on the level where
+// the human scores about half the points
+func adaptLevel(gs *GameState) string {
+	_, _, human, ok := cpuOpponent(gs)
+	if !gs.Config.Adaptive || !ok || gs.Game.Outcome() == chess.NoOutcome {
+		return ""
+	}
+	stats := LoadLevelStats()
+	result := humanResult(gs.Game.Outcome(), human)
+	stats.History = append(stats.History, LevelGame{time.Now().Format("2006.01.02"), stats.Level, result})
+	switch result {
+	case "win":
+		stats.Level = clampLevel(stats.Level + 1)
+	case "loss":
+		stats.Level = clampLevel(stats.Level - 1)
+	}
+	saveLevelStats(stats)
+	ApplyLevel(gs, stats.Level)
+	return fmt.Sprintf("Level %v", stats.Level)
+}
+
+// levelCmd shows the adaptive level and recent results or sets the level,
+// i.e., level 5
+func levelCmd(gs *GameState, args []string) string {
+	stats := LoadLevelStats()
+	if len(args) == 1 {
+		level, err := strconv.Atoi(args[0])
+		if err != nil || level != clampLevel(level) {
+			return fmt.Sprintf("\u26A0 Usage: level [0-%v]", maxLevel)
+		}
+		stats.Level = level
+		saveLevelStats(stats)
+		ApplyLevel(gs, level)
+	} else if len(args) > 1 {
+		return fmt.Sprintf("\u26A0 Usage: level [0-%v]", maxLevel)
+	}
+
+	depth, moveTime, skill := levelSettings(stats.Level)
+	history := stats.History
+	if len(history) > levelHistory {
+		history = history[len(history)-levelHistory:]
+	}
+	results := make([]string, 0, len(history))
+	for _, g := range history {
+		if g.Result != "" {
+			results = append(results, strings.ToUpper(g.Result[:1]))
+		}
+	}
+	mode := ""
+	if !gs.Config.Adaptive {
+		mode = " (adaptive off)"
+	}
+	return fmt.Sprintf("Level %v%v: depth %v, %vms, skill %v | %v",
+		stats.Level, mode, depth, int64(moveTime), skill, strings.Join(results, " "))
+}
@@ -0,0 +1,156 @@
+package uchess
+
+import (
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+// skillHeader declares the skill option set by the adaptive level
+const skillHeader = "option name Skill Level type spin default 20 min 0 max 20\n"
+
+func TestClampLevel(t *testing.T) {
+	for _, tc := range []struct {
+		level, want int
+	}{
+		{-3, 0},
+		{0, 0},
+		{defaultLevel, defaultLevel},
+		{maxLevel, maxLevel},
+		{maxLevel + 1, maxLevel},
+	} {
+		if got := clampLevel(tc.level); got != tc.want {
+			t.Errorf("clampLevel(%v) = %v, want %v", tc.level, got, tc.want)
+		}
+	}
+}
+
+func TestLevelSettings(t *testing.T) {
+	for _, tc := range []struct {
+		level    int
+		depth    int
+		moveTime time.Duration
+	}{
+		{0, 1, 50},
+		{defaultLevel, 2, 200},
+		{10, 6, 550},
+		{maxLevel, 11, 1050},
+	} {
+		depth, moveTime, skill := levelSettings(tc.level)
+		if depth != tc.depth || moveTime != tc.moveTime || skill != tc.level {
+			t.Errorf("levelSettings(%v) = %v, %v, %v, want %v, %v, %v",
+				tc.level, depth, moveTime, skill, tc.depth, tc.moveTime, tc.level)
+		}
+	}
+}
+
+func TestAdaptLevel(t *testing.T) {
+	foolsMate := []string{"f3", "e5", "g4", "Qh4#"}
+	for _, tc := range []struct {
+		name       string
+		config     Config
+		level      int
+		moves      []string
+		draw       bool
+		want       int    // Level afterwards
+		wantResult string // Result in the history, empty for no entry
+	}{
+		{"loss", Config{Adaptive: true, WhitePiece: "human", BlackPiece: "cpu"}, 5, foolsMate, false, 4, "loss"},
+		{"win", Config{Adaptive: true, WhitePiece: "cpu", BlackPiece: "human"}, 5, foolsMate, false, 6, "win"},
+		{"draw", Config{Adaptive: true, WhitePiece: "human", BlackPiece: "cpu"}, 5, []string{"e4"}, true, 5, "draw"},
+		{"lowest level", Config{Adaptive: true, WhitePiece: "human", BlackPiece: "cpu"}, 0, foolsMate, false, 0, "loss"},
+		{"highest level", Config{Adaptive: true, WhitePiece: "cpu", BlackPiece: "human"}, maxLevel, foolsMate, false, maxLevel, "win"},
+		{"unfinished", Config{Adaptive: true, WhitePiece: "human", BlackPiece: "cpu"}, 5, []string{"e4"}, false, 5, ""},
+		{"adaptive off", Config{WhitePiece: "human", BlackPiece: "cpu"}, 5, foolsMate, false, 5, ""},
+		{"two humans", Config{Adaptive: true, WhitePiece: "human", BlackPiece: "human"}, 5, foolsMate, false, 5, ""},
+	} {
+		os.Remove(levelPath())
+		if err := saveLevelStats(LevelStats{Level: tc.level}); err != nil {
+			t.Fatal(err)
+		}
+		cfg := mockEngine(t, "mock", skillHeader)
+		gs := newTestState(t, tc.config, cfg, cfg, cfg)
+		for _, m := range tc.moves {
+			if err := gs.Game.MoveStr(m); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if tc.draw {
+			gs.Game.Draw(chess.DrawOffer)
+		}
+		adaptLevel(gs)
+
+		stats := LoadLevelStats()
+		if stats.Level != tc.want {
+			t.Errorf("%v: level %v, want %v", tc.name, stats.Level, tc.want)
+		}
+		switch {
+		case tc.wantResult == "" && len(stats.History) != 0:
+			t.Errorf("%v: history %v, want none", tc.name, stats.History)
+		case tc.wantResult != "" && (len(stats.History) != 1 || stats.History[0].Result != tc.wantResult || stats.History[0].Level != tc.level):
+			t.Errorf("%v: history %v, want a %v at level %v", tc.name, stats.History, tc.wantResult, tc.level)
+		}
+		// The CPU opponent plays at the new level
+		if _, cpu, _, ok := cpuOpponent(gs); ok && tc.wantResult != "" {
+			depth, moveTime, _ := levelSettings(tc.want)
+			if cpu.Depth != depth || cpu.MoveTime != moveTime || !hasOption(cpu, skillOption, strconv.Itoa(tc.want)) {
+				t.Errorf("%v: cpu depth %v, movetime %v, options %v, want the settings of level %v",
+					tc.name, cpu.Depth, cpu.MoveTime, cpu.Options, tc.want)
+			}
+		}
+	}
+	os.Remove(levelPath())
+}
+
+// hasOption returns a bool indicating whether the config sets the option
+func hasOption(cfg *UCIEngine, name, value string) bool {
+	for _, opt := range cfg.Options {
+		if opt.Name == name && opt.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+func TestLevelCmd(t *testing.T) {
+	os.Remove(levelPath())
+	defer os.Remove(levelPath())
+	cfg := mockEngine(t, "mock", skillHeader)
+	log := mockLog(t, cfg)
+	gs := newTestState(t, Config{Adaptive: true, WhitePiece: "human", BlackPiece: "cpu"}, cfg, cfg, cfg)
+	if err := gs.UCI.UciBlack.NewGame(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{nil, "Level 3: depth 2, 200ms, skill 3 | "},
+		{[]string{"7"}, "Level 7: depth 4, 400ms, skill 7 | "},
+		{[]string{"21"}, "\u26A0 Usage: level [0-20]"},
+		{[]string{"-1"}, "\u26A0 Usage: level [0-20]"},
+		{[]string{"easy"}, "\u26A0 Usage: level [0-20]"},
+		{[]string{"1", "2"}, "\u26A0 Usage: level [0-20]"},
+	} {
+		if got := levelCmd(gs, tc.args); got != tc.want {
+			t.Errorf("levelCmd(%v) = %q, want %q", tc.args, got, tc.want)
+		}
+	}
+	if stats := LoadLevelStats(); stats.Level != 7 {
+		t.Errorf("level %v after the usage errors, want 7", stats.Level)
+	}
+	// The running engine plays at the new level
+	data, err := ioutil.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "setoption name Skill Level value 7") {
+		t.Errorf("engine read %q, want the skill level 7", data)
+	}
+}
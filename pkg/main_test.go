@@ -0,0 +1,145 @@
+package uchess
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/notnil/chess"
+	"github.com/tmountain/uchess/pkg/mockuci"
+)
+
+// Environment of the test binary run as a mock engine
+const (
+	mockScriptEnv  = "UCHESS_MOCKUCI_SCRIPT"  // Script to run
+	mockRestartEnv = "UCHESS_MOCKUCI_RESTART" // Script run by later starts
+	mockLogEnv     = "UCHESS_MOCKUCI_LOG"     // File the commands are appended to
+	mockProbeEnv   = "UCHESS_MOCKUCI_PROBE"   // File the arguments and working directory are written to
+)
+
+// userHome is the home directory the tests were started with, for tools
+// which cache there
+var userHome = os.Getenv("HOME")
+
+// TestMain runs the test binary as a mock engine when a test starts it with
+// a script, so engine crashes are real process exits
+func TestMain(m *testing.M) {
+	if file := os.Getenv(mockScriptEnv); file != "" {
+		os.Exit(runMock(file))
+	}
+	// Journals and finished games go to a scratch home directory
+	home, err := ioutil.TempDir("", "uchess-test")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	os.Setenv("HOME", home)
+	os.Setenv("USERPROFILE", home)
+	code := m.Run()
+	os.RemoveAll(home)
+	os.Exit(code)
+}
+
+// runMock runs the script as a mock engine and returns the exit code. With
+// a restart script, engines started after the first run that one instead.
+// With a probe file, the arguments and working directory are written to it
+func runMock(file string) int {
+	if restart := os.Getenv(mockRestartEnv); restart != "" {
+		marker := file + ".started"
+		if _, err := os.Stat(marker); err == nil {
+			file = restart
+		} else if err := ioutil.WriteFile(marker, nil, 0644); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return 1
+		}
+	}
+	if probe := os.Getenv(mockProbeEnv); probe != "" {
+		wd, _ := os.Getwd()
+		data := strings.Join(os.Args[1:], " ") + "\n" + wd + "\n"
+		if err := ioutil.WriteFile(probe, []byte(data), 0644); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return 1
+		}
+	}
+	script, err := mockuci.ParseFile(file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	var log io.Writer
+	if name := os.Getenv(mockLogEnv); name != "" {
+		f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return 1
+		}
+		defer f.Close()
+		log = f
+	}
+	return script.Run(os.Stdin, os.Stdout, log)
+}
+
+// mockEngine returns an engine config running the script as a mock engine
+func mockEngine(t *testing.T, name, script string) *UCIEngine {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), name+".script")
+	if err := ioutil.WriteFile(file, []byte(script), 0644); err != nil {
+		t.Fatal(err)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &UCIEngine{
+		Name:  name,
+		Path:  exe,
+		Depth: 1,
+		Env:   map[string]string{mockScriptEnv: file},
+	}
+}
+
+// mockLog returns the file the mock engine of the config logs the commands
+// it reads to
+func mockLog(t *testing.T, cfg *UCIEngine) string {
+	t.Helper()
+	cfg.Env[mockLogEnv] = filepath.Join(t.TempDir(), "commands.log")
+	return cfg.Env[mockLogEnv]
+}
+
+// mockRestart gives the mock engine of the config a script for its later
+// starts and returns the file logging the commands of every start
+func mockRestart(t *testing.T, cfg *UCIEngine, script string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "restart.script")
+	if err := ioutil.WriteFile(file, []byte(script), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Env[mockRestartEnv] = file
+	return mockLog(t, cfg)
+}
+
+// newTestState returns a game state with the engines of the configs. The
+// engines are closed when the test ends
+func newTestState(t *testing.T, config Config, white, black, hint *UCIEngine) *GameState {
+	t.Helper()
+	gs := &GameState{Game: chess.NewGame(), Config: config}
+	gs.UCI.CfgWhite, gs.UCI.CfgBlack, gs.UCI.CfgHint = white, black, hint
+	gs.UCI.UciWhite, gs.UCI.UciBlack, gs.UCI.UciHint = InitEngines(config, white, black, hint)
+	t.Cleanup(func() {
+		for _, eng := range []Engine{gs.UCI.UciWhite, gs.UCI.UciBlack, gs.UCI.UciHint} {
+			eng.Close()
+		}
+	})
+	return gs
+}
+
+// testConfig returns the default config with the given players
+func testConfig(white, black string) Config {
+	config := defaultConfig
+	config.WhitePiece, config.BlackPiece = white, black
+	return config
+}
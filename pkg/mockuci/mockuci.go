@@ -0,0 +1,357 @@
+// Package mockuci implements a scriptable UCI engine for tests. A script
+// declares the engine's id and options and how searches of each position
+// are answered: with info and bestmove lines, after a delay, by crashing or
+// by no longer responding.
+//
+//	id name Mock
+//	option name Hash type spin default 16 min 1 max 1024
+//
+//	position rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1
+//	delay 50ms
+//	info depth 12 score cp 35 pv e2e4 e7e5
+//	bestmove e2e4 ponder e7e5
+//
+//	position *
+//	crash 3
+//
+// Positions are matched on the board, side to move and castling rights of
+// the FEN, so clocks and en passant squares may be left out. Position *
+// answers any position without an answer of its own. Positions without any
+// answer are answered with their first legal move.
+//
+// Engines told xboard first speak CECP instead. They send the feature lines
+// of the script in reply to protover, answer ping and take positions from
+// setboard. The info and bestmove lines of answers are sent as thinking
+// output and move lines, send writes a line as it is, i.e., to answer in
+// algebraic notation:
+//
+//	feature myname="Mock" setboard=1 ping=1 done=1
+//
+//	position *
+//	send move O-O
+package mockuci
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+// anyPosition is the key of the answer to positions without one
+const anyPosition = "*"
+
+// Action kinds
+const (
+	actLine  = "line"  // Write a line
+	actSend  = "send"  // Write a line without translating it to CECP
+	actDelay = "delay" // Wait before the next action
+	actCrash = "crash" // Exit with a code
+	actHang  = "hang"  // Stop responding
+)
+
+// Action is a step of an answer to a search
+type Action struct {
+	Kind  string
+	Line  string
+	Delay time.Duration
+	Code  int
+}
+
+// Script is a parsed mockuci script
+type Script struct {
+	Header  []string            // id and option lines sent in reply to uci, feature lines to protover
+	Answers map[string][]Action // Answers by position key
+}
+
+// ParseFile reads a script file
+func ParseFile(file string) (*Script, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse reads a script
+func Parse(r io.Reader) (*Script, error) {
+	s := &Script{Answers: make(map[string][]Action)}
+	key := ""
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if fields[0] == "id" || fields[0] == "option" || fields[0] == "feature" {
+			s.Header = append(s.Header, line)
+			continue
+		}
+		if fields[0] == "position" {
+			arg := strings.TrimSpace(strings.TrimPrefix(line, "position"))
+			if arg != anyPosition {
+				if _, err := chess.FEN(completeFEN(arg)); err != nil {
+					return nil, fmt.Errorf("mockuci: line %v: %v", n, err)
+				}
+				arg = positionKey(arg)
+			}
+			key = arg
+			s.Answers[key] = make([]Action, 0)
+			continue
+		}
+		if key == "" {
+			return nil, fmt.Errorf("mockuci: line %v: %q outside of a position", n, line)
+		}
+		action, err := parseAction(fields, line)
+		if err != nil {
+			return nil, fmt.Errorf("mockuci: line %v: %v", n, err)
+		}
+		s.Answers[key] = append(s.Answers[key], action)
+	}
+	return s, scanner.Err()
+}
+
+// parseAction parses a step of an answer
+func parseAction(fields []string, line string) (Action, error) {
+	switch fields[0] {
+	case "info", "bestmove":
+		return Action{Kind: actLine, Line: line}, nil
+	case "send":
+		return Action{Kind: actSend, Line: strings.TrimSpace(strings.TrimPrefix(line, "send"))}, nil
+	case "delay":
+		if len(fields) != 2 {
+			return Action{}, fmt.Errorf("delay expects a duration")
+		}
+		d, err := time.ParseDuration(fields[1])
+		return Action{Kind: actDelay, Delay: d}, err
+	case "crash":
+		code := 1
+		if len(fields) > 1 {
+			var err error
+			if code, err = strconv.Atoi(fields[1]); err != nil {
+				return Action{}, fmt.Errorf("crash expects an exit code")
+			}
+		}
+		return Action{Kind: actCrash, Code: code}, nil
+	case "hang":
+		return Action{Kind: actHang}, nil
+	}
+	return Action{}, fmt.Errorf("unknown directive %q", fields[0])
+}
+
+// positionKey returns the fields of a FEN which identify the position
+func positionKey(fen string) string {
+	fields := strings.Fields(fen)
+	if len(fields) > 3 {
+		fields = fields[:3]
+	}
+	return strings.Join(fields, " ")
+}
+
+// completeFEN adds the en passant square and clocks when they are left out
+func completeFEN(fen string) string {
+	fields := strings.Fields(fen)
+	defaults := []string{"-", "0", "1"}
+	for len(fields) >= 3 && len(fields) < 6 {
+		fields = append(fields, defaults[len(fields)-3])
+	}
+	return strings.Join(fields, " ")
+}
+
+// answer returns the actions answering a search of the position
+func (s *Script) answer(pos *chess.Position) []Action {
+	if actions, ok := s.Answers[positionKey(pos.String())]; ok {
+		return actions
+	}
+	if actions, ok := s.Answers[anyPosition]; ok {
+		return actions
+	}
+	moves := pos.ValidMoves()
+	if len(moves) == 0 {
+		return []Action{{Kind: actLine, Line: "bestmove (none)"}}
+	}
+	move := chess.UCINotation{}.Encode(pos, moves[0])
+	return []Action{
+		{Kind: actLine, Line: "info depth 1 score cp 0 pv " + move},
+		{Kind: actLine, Line: "bestmove " + move},
+	}
+}
+
+// engine is the state of a running mock engine
+type engine struct {
+	script *Script
+	out    io.Writer
+	pos    *chess.Position
+	hung   bool // A hang action ran, nothing is answered anymore
+	cecp   bool // The engine speaks CECP
+}
+
+// Run answers the UCI commands read from in on out as scripted. Commands are
+// copied to log when it is not nil. The exit code is returned: the code of
+// a crash action, or 0 when the engine was told to quit or its input ended
+func (s *Script) Run(in io.Reader, out io.Writer, log io.Writer) int {
+	e := &engine{script: s, out: out, pos: chess.StartingPosition()}
+	pondering := false
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if log != nil {
+			fmt.Fprintln(log, line)
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 || e.hung {
+			if line == "quit" {
+				return 0
+			}
+			continue
+		}
+		switch fields[0] {
+		case "uci":
+			for _, h := range s.Header {
+				if !strings.HasPrefix(h, "feature") {
+					fmt.Fprintln(out, h)
+				}
+			}
+			fmt.Fprintln(out, "uciok")
+		case "xboard":
+			e.cecp = true
+		case "protover":
+			for _, h := range s.Header {
+				if strings.HasPrefix(h, "feature") {
+					fmt.Fprintln(out, h)
+				}
+			}
+		case "ping":
+			fmt.Fprintln(out, "pong "+strings.Join(fields[1:], " "))
+		case "setboard":
+			if err := e.setPosition(append([]string{"fen"}, fields[1:]...)); err != nil {
+				fmt.Fprintln(out, "tellusererror "+err.Error())
+			}
+		case "isready":
+			fmt.Fprintln(out, "readyok")
+		case "position":
+			if err := e.setPosition(fields[1:]); err != nil {
+				fmt.Fprintln(out, "info string "+err.Error())
+			}
+		case "go":
+			// Ponder searches are answered on ponderhit or stop
+			if len(fields) > 1 && fields[1] == "ponder" {
+				pondering = true
+				continue
+			}
+			if code, exit := e.search(); exit {
+				return code
+			}
+		case "ponderhit", "stop":
+			if !pondering {
+				continue
+			}
+			pondering = false
+			if code, exit := e.search(); exit {
+				return code
+			}
+		case "quit":
+			return 0
+		}
+	}
+	return 0
+}
+
+// setPosition sets up the position of a position command
+func (e *engine) setPosition(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("position: missing position")
+	}
+	moves := make([]string, 0)
+	for i, a := range args {
+		if a == "moves" {
+			moves = args[i+1:]
+			args = args[:i]
+			break
+		}
+	}
+	pos := chess.StartingPosition()
+	if args[0] == "fen" {
+		opt, err := chess.FEN(strings.Join(args[1:], " "))
+		if err != nil {
+			return err
+		}
+		pos = chess.NewGame(opt).Position()
+	}
+	for _, m := range moves {
+		move, err := chess.UCINotation{}.Decode(pos, m)
+		if err != nil {
+			return err
+		}
+		pos = pos.Update(move)
+	}
+	e.pos = pos
+	return nil
+}
+
+// search plays the answer to a search of the current position. It returns
+// the exit code and true when the answer crashes the engine
+func (e *engine) search() (int, bool) {
+	for _, a := range e.script.answer(e.pos) {
+		switch a.Kind {
+		case actLine:
+			if e.cecp {
+				fmt.Fprintln(e.out, cecpLine(a.Line))
+			} else {
+				fmt.Fprintln(e.out, a.Line)
+			}
+		case actSend:
+			fmt.Fprintln(e.out, a.Line)
+		case actDelay:
+			time.Sleep(a.Delay)
+		case actCrash:
+			return a.Code, true
+		case actHang:
+			e.hung = true
+			return 0, false
+		}
+	}
+	return 0, false
+}
+
+// cecpLine translates an info or bestmove line to CECP thinking output or a
+// move line. Info lines without a principal variation become comments
+func cecpLine(line string) string {
+	fields := strings.Fields(line)
+	if fields[0] == "bestmove" {
+		if len(fields) < 2 || fields[1] == "(none)" {
+			return "resign"
+		}
+		return "move " + fields[1]
+	}
+	var depth, score, ms, nodes int
+	for i := 1; i < len(fields)-1; i++ {
+		n, _ := strconv.Atoi(fields[i+1])
+		switch fields[i] {
+		case "depth":
+			depth = n
+		case "nodes":
+			nodes = n
+		case "time":
+			ms = n
+		case "cp":
+			score = n
+		case "mate":
+			score = 100000 + n
+			if n < 0 {
+				score = -100000 + n
+			}
+		case "pv":
+			pv := strings.Join(fields[i+1:], " ")
+			return fmt.Sprintf("%d %d %d %d %v", depth, score, ms/10, nodes, pv)
+		}
+	}
+	return "# " + line
+}
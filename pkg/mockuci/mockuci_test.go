@@ -0,0 +1,149 @@
+package mockuci
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const startFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+// run parses the script and runs it on the commands
+func run(t *testing.T, script string, cmds ...string) (string, int) {
+	t.Helper()
+	s, err := Parse(strings.NewReader(script))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	code := s.Run(strings.NewReader(strings.Join(cmds, "\n")+"\n"), &out, nil)
+	return out.String(), code
+}
+
+func TestParse(t *testing.T) {
+	s, err := Parse(strings.NewReader(`
+# comment
+id name Mock
+option name Hash type spin default 16 min 1 max 1024
+
+position ` + startFEN + `
+delay 10ms
+info depth 1 score cp 35 pv e2e4
+bestmove e2e4
+
+position *
+crash 3
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Header) != 2 {
+		t.Errorf("header has %v lines, want 2", len(s.Header))
+	}
+	if got := len(s.Answers[positionKey(startFEN)]); got != 3 {
+		t.Errorf("start position has %v actions, want 3", got)
+	}
+	if a := s.Answers[anyPosition]; len(a) != 1 || a[0].Kind != actCrash || a[0].Code != 3 {
+		t.Errorf("any position answer = %+v, want crash 3", a)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, script := range []string{
+		"bestmove e2e4",
+		"position not a fen",
+		"position *\ndelay soon",
+		"position *\ncrash hard",
+		"position *\nexplode",
+	} {
+		if _, err := Parse(strings.NewReader(script)); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", script)
+		}
+	}
+}
+
+func TestRunHandshake(t *testing.T) {
+	out, code := run(t, "id name Mock\noption name Hash type spin default 16 min 1 max 1024\n", "uci", "isready", "quit")
+	want := "id name Mock\noption name Hash type spin default 16 min 1 max 1024\nuciok\nreadyok\n"
+	if out != want || code != 0 {
+		t.Errorf("got %q (exit %v), want %q (exit 0)", out, code, want)
+	}
+}
+
+func TestRunScriptedPosition(t *testing.T) {
+	script := "position rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq\nbestmove c7c5\n"
+	// Clocks and en passant squares do not take part in matching
+	out, _ := run(t, script, "position fen "+startFEN+" moves e2e4", "go depth 1")
+	if out != "bestmove c7c5\n" {
+		t.Errorf("got %q, want bestmove c7c5", out)
+	}
+}
+
+func TestRunDefaultAnswer(t *testing.T) {
+	out, _ := run(t, "", "position startpos", "go depth 1")
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "info ") || !strings.HasPrefix(lines[1], "bestmove ") {
+		t.Errorf("got %q, want an info and a bestmove line", out)
+	}
+	mated := "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3"
+	if out, _ := run(t, "", "position fen "+mated, "go"); out != "bestmove (none)\n" {
+		t.Errorf("got %q, want bestmove (none)", out)
+	}
+}
+
+func TestRunCrash(t *testing.T) {
+	out, code := run(t, "position *\ninfo depth 1 score cp 0 pv e2e4\ncrash 3\n", "position startpos", "go", "isready")
+	if code != 3 {
+		t.Errorf("exit code %v, want 3", code)
+	}
+	if strings.Contains(out, "readyok") {
+		t.Errorf("crashed engine answered %q", out)
+	}
+}
+
+func TestRunHang(t *testing.T) {
+	out, code := run(t, "position *\nhang\n", "position startpos", "go", "isready", "quit")
+	if out != "" || code != 0 {
+		t.Errorf("got %q (exit %v), want no answer (exit 0)", out, code)
+	}
+}
+
+func TestRunPonder(t *testing.T) {
+	script := "position *\nbestmove e2e4\n"
+	if out, _ := run(t, script, "position startpos", "go ponder"); out != "" {
+		t.Errorf("ponder search answered %q before ponderhit", out)
+	}
+	for _, end := range []string{"ponderhit", "stop"} {
+		if out, _ := run(t, script, "position startpos", "go ponder", end); out != "bestmove e2e4\n" {
+			t.Errorf("%v: got %q, want bestmove e2e4", end, out)
+		}
+	}
+}
+
+func TestRunCECP(t *testing.T) {
+	script := `
+id name Mock
+feature myname="Mock" setboard=1 ping=1 done=1
+
+position rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq
+info depth 12 score cp -20 nodes 4500 time 250 pv c7c5 g1f3
+info depth 13 score mate -3 pv c7c5
+bestmove c7c5
+
+position *
+send move O-O
+`
+	out, code := run(t, script, "xboard", "protover 2", "accepted myname", "ping 1",
+		"setboard rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", "sd 12", "go",
+		"setboard "+startFEN, "go", "quit")
+	want := `feature myname="Mock" setboard=1 ping=1 done=1
+pong 1
+12 -20 25 4500 c7c5 g1f3
+13 -100003 0 0 c7c5
+move c7c5
+move O-O
+`
+	if out != want || code != 0 {
+		t.Errorf("got %q (exit %v), want %q (exit 0)", out, code, want)
+	}
+}
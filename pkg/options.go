@@ -0,0 +1,217 @@
+package uchess
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EngineOption is an option declared by the engine in the uci handshake,
+// i.e., option name Skill Level type spin default 20 min 0 max 20
+type EngineOption struct {
+	Name    string
+	Type    string
+	Default string
+	Min     string
+	Max     string
+	Vars    []string
+}
+
+// optionKeywords separate the fields of an option declaration
+var optionKeywords = map[string]bool{"name": true, "type": true, "default": true, "min": true, "max": true, "var": true}
+
+// ParseOption parses an option declaration. Unlike the uci module, names and
+// values may contain spaces
+func ParseOption(line string) (EngineOption, error) {
+	var o EngineOption
+	fields := strings.Fields(line)
+	if len(fields) == 0 || fields[0] != "option" {
+		return o, errors.New("uci: invalid option line")
+	}
+	key := ""
+	value := make([]string, 0)
+	endField := func() {
+		v := strings.Join(value, " ")
+		value = value[:0]
+		switch key {
+		case "name":
+			o.Name = v
+		case "type":
+			o.Type = v
+		case "default":
+			// Engines send <empty> for empty strings
+			if v == "<empty>" {
+				v = ""
+			}
+			o.Default = v
+		case "min":
+			o.Min = v
+		case "max":
+			o.Max = v
+		case "var":
+			o.Vars = append(o.Vars, v)
+		}
+	}
+	for _, f := range fields[1:] {
+		if optionKeywords[f] && !(key == "name" && len(value) == 0) {
+			endField()
+			key = f
+			continue
+		}
+		value = append(value, f)
+	}
+	endField()
+	// A combo without values leaves nothing to choose from
+	if o.Name == "" || o.Type == "" || o.Type == "combo" && len(o.Vars) == 0 {
+		return o, errors.New("uci: invalid option line")
+	}
+	return o, nil
+}
+
+// Validate returns an error when the option does not accept the value
+func (o EngineOption) Validate(value string) error {
+	switch o.Type {
+	case "check":
+		if value != "true" && value != "false" {
+			return fmt.Errorf("%v expects true or false, not %q", o.Name, value)
+		}
+	case "spin":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%v expects a number, not %q", o.Name, value)
+		}
+		if min, err := strconv.Atoi(o.Min); err == nil && n < min {
+			return fmt.Errorf("%v is below the minimum of %v", o.Name, min)
+		}
+		if max, err := strconv.Atoi(o.Max); err == nil && n > max {
+			return fmt.Errorf("%v is above the maximum of %v", o.Name, max)
+		}
+	case "combo":
+		for _, v := range o.Vars {
+			if strings.EqualFold(v, value) {
+				return nil
+			}
+		}
+		return fmt.Errorf("%v expects one of %v, not %q", o.Name, strings.Join(o.Vars, ", "), value)
+	}
+	return nil
+}
+
+// Range describes the values accepted by the option
+func (o EngineOption) Range() string {
+	switch {
+	case o.Type == "spin":
+		return fmt.Sprintf("%v..%v", o.Min, o.Max)
+	case o.Type == "combo":
+		return strings.Join(o.Vars, ", ")
+	}
+	return ""
+}
+
+// findOption looks up an option by name ignoring case
+func findOption(options []EngineOption, name string) (EngineOption, bool) {
+	for _, o := range options {
+		if strings.EqualFold(o.Name, name) {
+			return o, true
+		}
+	}
+	return EngineOption{}, false
+}
+
+// OptionWarning summarizes the option warnings of the engines started
+// since the last call for the message label. An empty string is returned
+// when there are none
+func OptionWarning(us UCIState) string {
+	seen := make(map[string]bool)
+	warnings := make([]string, 0)
+	for _, eng := range []Engine{us.UciWhite, us.UciBlack, us.UciHint} {
+		d, ok := eng.(driver)
+		if !ok {
+			continue
+		}
+		for _, w := range d.newWarnings() {
+			if !seen[w] {
+				seen[w] = true
+				warnings = append(warnings, w)
+			}
+		}
+	}
+	switch len(warnings) {
+	case 0:
+		return ""
+	case 1:
+		return "\u26A0 " + warnings[0]
+	}
+	return fmt.Sprintf("\u26A0 %v (+%v more, see uchess engine-info)", warnings[0], len(warnings)-1)
+}
+
+// optionCmds returns the options to send for an engine config. Options
+// uchess sets on its own are only sent when the engine declares them, and
+// configured options which the engine does not declare or which have an
+// invalid value are skipped with a warning
+func optionCmds(cfg *UCIEngine, config Config, declared []EngineOption) ([]Option, []string) {
+	cmds := make([]Option, 0)
+	warnings := make([]string, 0)
+	add := func(name, value string, required bool) {
+		o, ok := findOption(declared, name)
+		if !ok {
+			if required {
+				warnings = append(warnings, fmt.Sprintf("%v: unknown option %v", cfg.Name, name))
+			}
+			return
+		}
+		if err := o.Validate(value); err != nil {
+			warnings = append(warnings, fmt.Sprintf("%v: %v", cfg.Name, err.Error()))
+			return
+		}
+		cmds = append(cmds, Option{o.Name, value})
+	}
+
+	add("Hash", strconv.Itoa(cfg.Hash), false)
+	add("Ponder", strconv.FormatBool(cfg.Ponder), false)
+	add("OwnBook", strconv.FormatBool(cfg.OwnBook), false)
+	add("MultiPV", strconv.Itoa(cfg.MultiPV), false)
+	if config.SyzygyPath != "" {
+		add("SyzygyPath", config.SyzygyPath, true)
+	}
+	if config.Chess960 != "" {
+		add("UCI_Chess960", "true", true)
+	}
+	// Send any custom options that were specified
+	for _, option := range cfg.Options {
+		add(option.Name, option.Value, true)
+	}
+	return cmds, warnings
+}
+
+// EngineInfo holds the id and options an engine declares in the handshake
+type EngineInfo struct {
+	ID      map[string]string
+	Options []EngineOption
+}
+
+// ProbeEngine starts an engine, runs the handshake and shuts the engine
+// down again
+func ProbeEngine(cfg *UCIEngine) (EngineInfo, error) {
+	eng := newDriver(cfg, Config{})
+	// A process started by a failed handshake is shut down as well
+	defer eng.Close()
+	if err := eng.probe(); err != nil {
+		return EngineInfo{}, err
+	}
+	options, err := eng.Options()
+	if err != nil {
+		return EngineInfo{}, err
+	}
+	sort.Slice(options, func(i, j int) bool { return strings.ToLower(options[i].Name) < strings.ToLower(options[j].Name) })
+	return EngineInfo{eng.ID(), options}, nil
+}
+
+// CheckOptions returns the problems with the configured options of an
+// engine
+func CheckOptions(cfg *UCIEngine, config Config, info EngineInfo) []string {
+	_, warnings := optionCmds(cfg, config, info.Options)
+	return warnings
+}
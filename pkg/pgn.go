@@ -0,0 +1,343 @@
+package uchess
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+// pgnLineWidth is the maximum width of a line of PGN movetext
+const pgnLineWidth = 80
+
+// rosterTags is the seven tag roster in the order required by the PGN standard
+var rosterTags = []string{"Event", "Site", "Date", "Round", "White", "Black", "Result"}
+
+// tagEscaper escapes PGN tag values
+var tagEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// pgnNote holds the commentary attached to a position in PGN movetext.
+// Notes are keyed by ply, so the note for ply n follows the nth move
+// and the note for ply 0 precedes the first move
+type pgnNote struct {
+	NAGs      []int
+	Comment   string
+	Variation []*chess.Move // Alternative to the move leading to the ply
+}
+
+// moveNumber returns the full move number of a position
+func moveNumber(pos *chess.Position) int {
+	fields := strings.Fields(pos.String())
+	if len(fields) < 6 {
+		return 1
+	}
+	n, err := strconv.Atoi(fields[5])
+	if err != nil {
+		return 1
+	}
+	return n
+}
+
+// wrapTokens joins tokens with spaces, breaking lines at the given width
+func wrapTokens(tokens []string, width int) string {
+	var sb strings.Builder
+	lineLen := 0
+	for _, tok := range tokens {
+		if lineLen > 0 && lineLen+1+len(tok) > width {
+			sb.WriteString("\n")
+			lineLen = 0
+		} else if lineLen > 0 {
+			sb.WriteString(" ")
+			lineLen++
+		}
+		sb.WriteString(tok)
+		lineLen += len(tok)
+	}
+	return sb.String()
+}
+
+// variationTokens encodes a line of moves played from pos as a PGN variation.
+// The line is cut short at the first illegal move
+func variationTokens(pos *chess.Position, moves []*chess.Move) []string {
+	tokens := make([]string, 0)
+	for i, m := range moves {
+		move := findMove(pos, m)
+		if move == nil {
+			break
+		}
+		if pos.Turn() == chess.White {
+			tokens = append(tokens, fmt.Sprintf("%v.", moveNumber(pos)))
+		} else if i == 0 {
+			tokens = append(tokens, fmt.Sprintf("%v...", moveNumber(pos)))
+		}
+		tokens = append(tokens, chess.AlgebraicNotation{}.Encode(pos, move))
+		pos = pos.Update(move)
+	}
+	if len(tokens) > 0 {
+		tokens[0] = "(" + tokens[0]
+		tokens[len(tokens)-1] += ")"
+	}
+	return tokens
+}
+
+// pgnTags returns the tags of a game with the seven tag roster first
+func pgnTags(game *chess.Game) []*chess.TagPair {
+	tags := make([]*chess.TagPair, 0)
+	for _, key := range rosterTags {
+		if tag := game.GetTagPair(key); tag != nil {
+			tags = append(tags, tag)
+		}
+	}
+	for _, tag := range game.TagPairs() {
+		isRoster := false
+		for _, key := range rosterTags {
+			isRoster = isRoster || tag.Key == key
+		}
+		if !isRoster {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// cloneGame returns a copy of a game with tag pairs of its own. Clones made
+// by the chess module share their tag pairs with the game
+func cloneGame(game *chess.Game) *chess.Game {
+	clone := game.Clone()
+	for _, tag := range clone.TagPairs() {
+		clone.RemoveTagPair(tag.Key)
+		clone.AddTagPair(tag.Key, tag.Value)
+	}
+	return clone
+}
+
+// encodePGN encodes a game as PGN including any notes attached to its plies.
+// The chess module's encoder does not support comments, hence this one
+func encodePGN(game *chess.Game, notes map[int]pgnNote) string {
+	var sb strings.Builder
+	for _, tag := range pgnTags(game) {
+		fmt.Fprintf(&sb, "[%v \"%v\"]\n", tag.Key, tagEscaper.Replace(tag.Value))
+	}
+	sb.WriteString("\n")
+
+	tokens := make([]string, 0)
+	// Black needs a move number after a comment or on the first move
+	needNumber := true
+	addNote := func(ply int) {
+		note, ok := notes[ply]
+		if !ok {
+			return
+		}
+		for _, nag := range note.NAGs {
+			tokens = append(tokens, fmt.Sprintf("$%v", nag))
+		}
+		if note.Comment != "" {
+			tokens = append(tokens, fmt.Sprintf("{%v}", note.Comment))
+			needNumber = true
+		}
+		if ply > 0 && len(note.Variation) > 0 {
+			variation := variationTokens(game.Positions()[ply-1], note.Variation)
+			tokens = append(tokens, variation...)
+			needNumber = needNumber || len(variation) > 0
+		}
+	}
+
+	addNote(0)
+	positions := game.Positions()
+	for i, move := range game.Moves() {
+		pos := positions[i]
+		san := chess.AlgebraicNotation{}.Encode(pos, move)
+		if pos.Turn() == chess.White {
+			tokens = append(tokens, fmt.Sprintf("%v.", moveNumber(pos)))
+		} else if needNumber {
+			tokens = append(tokens, fmt.Sprintf("%v...", moveNumber(pos)))
+		}
+		tokens = append(tokens, san)
+		needNumber = false
+		addNote(i + 1)
+	}
+	tokens = append(tokens, string(game.Outcome()))
+	sb.WriteString(wrapTokens(tokens, pgnLineWidth))
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// isResult returns a bool indicating whether s is a PGN game termination marker
+func isResult(s string) bool {
+	return s == "*" || s == "1-0" || s == "0-1" || s == "1/2-1/2"
+}
+
+// SplitPGN splits the contents of a PGN file into individual games. Games
+// end at their termination marker or when a tag pair follows movetext
+func SplitPGN(data string) []string {
+	games := make([]string, 0)
+	var sb strings.Builder
+	var scan pgnScanner
+	hasMoves := false
+	endGame := func() {
+		if hasMoves {
+			games = append(games, sb.String())
+		}
+		sb.Reset()
+		hasMoves = false
+	}
+
+	for _, line := range strings.Split(data, "\n") {
+		trimmed := strings.TrimSpace(line)
+		isTag := scan.tag(trimmed)
+		if isTag && hasMoves {
+			endGame()
+		}
+		sb.WriteString(line + "\n")
+		if trimmed == "" || isTag {
+			continue
+		}
+		hasMoves = true
+		if fields := strings.Fields(trimmed); !scan.comment && isResult(fields[len(fields)-1]) {
+			endGame()
+		}
+	}
+	endGame()
+	return games
+}
+
+// tagRegex matches a tag pair line, i.e., [Event "Casual Game"]
+var tagRegex = regexp.MustCompile(`^\[\s*(\w+)\s+"((?:[^"\\]|\\.)*)"\s*\]$`)
+
+// pgnScanner classifies the lines of PGN text. It follows brace comments
+// across lines so that a comment line starting with [, i.e., a wrapped
+// [%clk 0:01:00], is not taken for a tag pair
+type pgnScanner struct {
+	comment bool // Inside a brace comment
+}
+
+// tag returns true when the line is a tag pair, otherwise the line is
+// scanned for the start and end of comments
+func (p *pgnScanner) tag(line string) bool {
+	if !p.comment && tagRegex.MatchString(line) {
+		return true
+	}
+	for _, c := range line {
+		switch {
+		case p.comment && c == '}':
+			p.comment = false
+		case !p.comment && c == '{':
+			p.comment = true
+		// Rest of line comments end the line
+		case !p.comment && c == ';':
+			return false
+		}
+	}
+	return false
+}
+
+var nagRegex = regexp.MustCompile(`(^|\s)\$\d+`)
+
+// DecodePGN decodes a single game. The movetext is joined onto one line
+// first since the chess module cannot strip comments or variations that
+// span multiple lines
+func DecodePGN(pgn string) (*chess.Game, error) {
+	var tags, moves []string
+	var scan pgnScanner
+	for _, line := range strings.Split(pgn, "\n") {
+		trimmed := strings.TrimSpace(line)
+		// Tags precede the movetext
+		if len(moves) == 0 && scan.tag(trimmed) {
+			tags = append(tags, trimmed)
+		} else if trimmed != "" {
+			scan.tag(trimmed)
+			moves = append(moves, trimmed)
+		}
+	}
+	// The chess module does not understand numeric annotation glyphs
+	movetext := nagRegex.ReplaceAllString(strings.Join(moves, " "), "")
+	text := strings.Join(tags, "\n") + "\n\n" + movetext
+	opt, err := chess.PGN(strings.NewReader(text))
+	if err != nil {
+		return nil, err
+	}
+	return chess.NewGame(opt), nil
+}
+
+// pgnComments extracts the comments from the movetext of a single game
+// keyed by ply in the same manner as pgnNote. Comments inside variations
+// are ignored
+func pgnComments(pgn string) map[int]string {
+	comments := make(map[int]string)
+	ply := 0
+	depth := 0
+	inTag := false
+	token := ""
+
+	endToken := func() {
+		tok := token
+		token = ""
+		if tok == "" || depth > 0 {
+			return
+		}
+		// Strip move numbers (i.e., 12. or 12...)
+		if idx := strings.LastIndex(tok, "."); idx >= 0 {
+			tok = tok[idx+1:]
+		}
+		switch {
+		case tok == "", strings.HasPrefix(tok, "$"):
+		case isResult(tok):
+		default:
+			ply++
+		}
+	}
+
+	addComment := func(c string) {
+		if depth > 0 {
+			return
+		}
+		c = strings.TrimSpace(c)
+		if prev, ok := comments[ply]; ok {
+			c = prev + " " + c
+		}
+		comments[ply] = c
+	}
+
+	for i := 0; i < len(pgn); i++ {
+		c := pgn[i]
+		switch {
+		case inTag:
+			if c == ']' {
+				inTag = false
+			}
+		case c == '[':
+			endToken()
+			inTag = true
+		case c == '{':
+			endToken()
+			end := strings.IndexByte(pgn[i:], '}')
+			if end < 0 {
+				end = len(pgn) - i
+			}
+			addComment(pgn[i+1 : i+end])
+			i += end
+		case c == ';':
+			endToken()
+			end := strings.IndexByte(pgn[i:], '\n')
+			if end < 0 {
+				end = len(pgn) - i
+			}
+			addComment(pgn[i+1 : i+end])
+			i += end
+		case c == '(':
+			endToken()
+			depth++
+		case c == ')':
+			endToken()
+			depth--
+		case c == ' ' || c == '\n' || c == '\r' || c == '\t':
+			endToken()
+		default:
+			token += string(c)
+		}
+	}
+	endToken()
+	return comments
+}
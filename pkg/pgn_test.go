@@ -0,0 +1,99 @@
+package uchess
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+// clockPGN has movetext comments wrapped so that a line starts with [
+const clockPGN = `[Event "Rated Blitz game"]
+[White "a \"quoted\" name"]
+[Black "b"]
+
+1. e4 { [%eval 0.2]
+[%clk 0:03:00] } 1... e5 { [%clk 0:03:00] }
+2. Nf3 {
+[%clk 0:02:58] } 2... Nc6 *
+
+[Event "Second"]
+
+1. d4 d5 1/2-1/2
+`
+
+func TestSplitPGN(t *testing.T) {
+	games := SplitPGN(clockPGN)
+	if len(games) != 2 {
+		t.Fatalf("SplitPGN() returned %v games, want 2", len(games))
+	}
+	if !strings.Contains(games[0], "Nc6") || !strings.Contains(games[1], "Second") {
+		t.Errorf("games split at the wrong lines:\n%q", games)
+	}
+}
+
+func TestSplitPGNWithoutResult(t *testing.T) {
+	// A game without a result ends at the tags of the next game
+	games := SplitPGN("[Event \"a\"]\n\n1. e4 e5\n[Event \"b\"]\n\n1. d4 *\n")
+	if len(games) != 2 {
+		t.Errorf("SplitPGN() returned %v games, want 2", len(games))
+	}
+}
+
+func TestDecodePGN(t *testing.T) {
+	game, err := DecodePGN(SplitPGN(clockPGN)[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(game.Moves()); n != 4 {
+		t.Errorf("game has %v moves, want 4", n)
+	}
+	if tag := game.GetTagPair("Black"); tag == nil || tag.Value != "b" {
+		t.Errorf("Black tag = %v, want b", tag)
+	}
+}
+
+func TestPGNScannerTag(t *testing.T) {
+	for _, tc := range []struct {
+		line string
+		tag  bool
+	}{
+		{`[Event "Casual Game"]`, true},
+		{`[White "a \"quoted\" name"]`, true},
+		{`[%clk 0:03:00] }`, false},
+		{`[Event]`, false},
+		{`1. e4 [x]`, false},
+	} {
+		var scan pgnScanner
+		if got := scan.tag(tc.line); got != tc.tag {
+			t.Errorf("tag(%q) = %v, want %v", tc.line, got, tc.tag)
+		}
+	}
+	// Tag pairs inside a comment are part of the comment
+	var scan pgnScanner
+	scan.tag("1. e4 { a comment")
+	if scan.tag(`[Event "x"]`) {
+		t.Error("a line inside a comment was taken for a tag")
+	}
+}
+
+func TestAnalysisPGNKeepsGame(t *testing.T) {
+	game := chess.NewGame()
+	game.AddTagPair("Opening", "Unknown")
+	for _, m := range []string{"e4", "e5", "Nf3", "Nc6"} {
+		if err := game.MoveStr(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pgn := AnalysisPGN(game, GameAnalysis{})
+	if !strings.Contains(pgn, `[ECO "C44"]`) {
+		t.Errorf("AnalysisPGN() = %q, want the ECO tag", pgn)
+	}
+	// The tags go on a copy of the game
+	if tag := game.GetTagPair("ECO"); tag != nil {
+		t.Errorf("game tagged ECO %v, want no ECO tag", tag.Value)
+	}
+	if tag := game.GetTagPair("Opening"); tag == nil || tag.Value != "Unknown" {
+		t.Errorf("game tagged Opening %v, want Unknown", tag)
+	}
+}
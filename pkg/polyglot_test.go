@@ -0,0 +1,155 @@
+package uchess
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+// Polyglot keys published with the book format for the start position and
+// the positions after some moves from it
+const (
+	keyStart = 0x463b96181691fc9c
+	keyE4    = 0x823c9b50fd114196
+	keyE4D5  = 0x0756b94461c50fb0
+)
+
+// Polyglot moves of the book fixture
+const (
+	bookE2E4 = 4<<6 | 1<<9 | 4 | 3<<3
+	bookD2D4 = 3<<6 | 1<<9 | 3 | 3<<3
+	bookE2E5 = 4<<6 | 1<<9 | 4 | 4<<3
+	bookE7E5 = 4<<6 | 6<<9 | 4 | 4<<3
+	bookE4D5 = 4<<6 | 3<<9 | 3 | 4<<3
+)
+
+// writeBook writes the entries, sorted by key, as a Polyglot book and
+// returns its path
+func writeBook(t *testing.T, entries []polyglotEntry) string {
+	t.Helper()
+	data := make([]byte, 0)
+	for _, e := range entries {
+		b := make([]byte, polyglotEntrySize)
+		binary.BigEndian.PutUint64(b[0:8], e.key)
+		binary.BigEndian.PutUint16(b[8:10], e.move)
+		binary.BigEndian.PutUint16(b[10:12], e.weight)
+		data = append(data, b...)
+	}
+	file := filepath.Join(t.TempDir(), "book.bin")
+	if err := ioutil.WriteFile(file, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestPolyglotKey(t *testing.T) {
+	for _, tc := range []struct {
+		moves []string
+		want  uint64
+	}{
+		{nil, keyStart},
+		// The e3 en passant square counts only when a pawn can take
+		{[]string{"e2e4"}, keyE4},
+		{[]string{"e2e4", "d7d5"}, keyE4D5},
+		{[]string{"e2e4", "d7d5", "e4e5"}, 0x662fafb965db29d4},
+		{[]string{"e2e4", "d7d5", "e4e5", "f7f5"}, 0x22a48b5a8e47ff78},
+		// The white king loses its castling rights
+		{[]string{"e2e4", "d7d5", "e4e5", "f7f5", "e1e2"}, 0x652a607ca3f242c1},
+		{[]string{"e2e4", "d7d5", "e4e5", "f7f5", "e1e2", "e8f7"}, 0x00fdd303c946bdd9},
+		{[]string{"a2a4", "b7b5", "h2h4", "b5b4", "c2c4"}, 0x3c8123ea7b067637},
+		// The en passant capture and the rook losing white's queen side rights
+		{[]string{"a2a4", "b7b5", "h2h4", "b5b4", "c2c4", "b4c3", "a1a3"}, 0x5c3f9b829b279560},
+	} {
+		game := chess.NewGame(chess.UseNotation(chess.UCINotation{}))
+		for _, m := range tc.moves {
+			if err := game.MoveStr(m); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := PolyglotKey(game.Position()); got != tc.want {
+			t.Errorf("PolyglotKey(%v) = %#016x, want %#016x", tc.moves, got, tc.want)
+		}
+	}
+}
+
+func TestDecodeBookMove(t *testing.T) {
+	promotion := "8/4P1k1/8/8/8/8/8/4K3 w - - 0 1"
+	for _, tc := range []struct {
+		fen  string
+		move uint16
+		want string // UCI notation, empty for a move which is not legal
+	}{
+		{startKey + " - 0 1", bookE2E4, "e2e4"},
+		{startKey + " - 0 1", bookE2E5, ""},
+		// Castling is encoded as the king taking its own rook
+		{kingE1FEN, 4<<6 | 7, "e1g1"},
+		{kingE1FEN, 4 << 6, "e1c1"},
+		{promotion, 4<<12 | 4<<6 | 6<<9 | 4 | 7<<3, "e7e8q"},
+		{promotion, 1<<12 | 4<<6 | 6<<9 | 4 | 7<<3, "e7e8n"},
+		{promotion, 5<<12 | 4<<6 | 6<<9 | 4 | 7<<3, ""},
+	} {
+		m := decodeBookMove(position(t, tc.fen), tc.move)
+		switch {
+		case tc.want == "" && m != nil:
+			t.Errorf("%v: decodeBookMove(%#x) = %v, want nil", tc.fen, tc.move, m)
+		case tc.want != "" && (m == nil || m.String() != tc.want):
+			t.Errorf("%v: decodeBookMove(%#x) = %v, want %v", tc.fen, tc.move, m, tc.want)
+		}
+	}
+}
+
+func TestBookMoves(t *testing.T) {
+	book, err := LoadBook(writeBook(t, []polyglotEntry{
+		{keyE4D5, bookE4D5, 1},
+		{keyStart, bookE2E4, 10},
+		{keyStart, bookE2E5, 50},
+		{keyStart, bookD2D4, 30},
+		{keyE4, bookE7E5, 0},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	game := chess.NewGame()
+	// Moves which are not legal are skipped and the rest ordered by weight
+	moves := book.Moves(game.Position())
+	if len(moves) != 2 || moves[0].Move.String() != "d2d4" || moves[0].Weight != 30 || moves[1].Move.String() != "e2e4" {
+		t.Errorf("Moves() = %v, want d2d4 and e2e4", moves)
+	}
+	if m := book.Pick(game.Position(), BookBest); m == nil || m.String() != "d2d4" {
+		t.Errorf("Pick(best) = %v, want d2d4", m)
+	}
+	book.rnd = rand.New(rand.NewSource(1))
+	picked := make(map[string]int)
+	for i := 0; i < 200; i++ {
+		picked[book.Pick(game.Position(), BookRandom).String()]++
+	}
+	if len(picked) != 2 || picked["d2d4"] < picked["e2e4"] {
+		t.Errorf("Pick(random) picked %v, want both moves with d2d4 more often", picked)
+	}
+	// Entries without weight are still played
+	game.MoveStr("e4")
+	if m := book.Pick(game.Position(), BookRandom); m == nil || m.String() != "e7e5" {
+		t.Errorf("Pick() = %v, want e7e5", m)
+	}
+	game.MoveStr("e5")
+	if m := book.Pick(game.Position(), BookBest); m != nil {
+		t.Errorf("Pick() = %v out of book, want nil", m)
+	}
+}
+
+func TestLoadBookInvalid(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "book.bin")
+	if err := ioutil.WriteFile(file, make([]byte, polyglotEntrySize+1), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadBook(file); err == nil {
+		t.Error("LoadBook() succeeded, want an error for a truncated entry")
+	}
+	if _, err := LoadBook(filepath.Join(t.TempDir(), "missing.bin")); err == nil {
+		t.Error("LoadBook() succeeded, want an error for a missing book")
+	}
+}
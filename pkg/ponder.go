@@ -0,0 +1,163 @@
+package uchess
+
+import (
+	"time"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/uci"
+)
+
+// ponderSearch is a go ponder search running while the opponent is to
+// move. Its output is read on a goroutine until the best move arrives
+type ponderSearch struct {
+	fen     string           // Position after the expected reply
+	cmdGo   uci.CmdGo        // Limits of the search
+	lines   map[int]uci.Info // Lines of the search by multipv
+	results uci.SearchResults
+	err     error
+	done    chan struct{} // Closed once the best move was read
+}
+
+// Ponder starts pondering on the expected reply to the position. The
+// search runs until the next search, which sends ponderhit when the reply
+// was played or stops it otherwise. Engines not started yet do not ponder
+func (e *uciDriver) Ponder(pos *chess.Position, reply *chess.Move, cmdGo uci.CmdGo) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.proc == nil {
+		return nil
+	}
+	fen := pos.Update(reply).String()
+	if e.ponder != nil && e.ponder.fen == fen {
+		return nil
+	}
+	if err := e.stopPonder(); err != nil {
+		return err
+	}
+	e.proc.drain()
+	// Engines in Chess960 mode castle by taking their own rook
+	if e.chess960Mode() {
+		reply = engineCastle(pos, reply)
+	}
+	cmdPos := uci.CmdPosition{Position: pos, Moves: []*chess.Move{reply}}
+	if err := e.proc.send(cmdPos.String()); err != nil {
+		return err
+	}
+	cmdGo.Ponder = true
+	if err := e.proc.send(cmdGo.String()); err != nil {
+		return err
+	}
+
+	p := &ponderSearch{fen, cmdGo, make(map[int]uci.Info), uci.SearchResults{}, nil, make(chan struct{})}
+	go func(proc *engineProcess) {
+		p.results, p.err = readSearch(proc, 0, p.lines)
+		close(p.done)
+	}(e.proc)
+	e.ponder = p
+	return nil
+}
+
+// Pondering returns a bool indicating whether a ponder search runs
+func (e *uciDriver) Pondering() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.proc != nil && e.ponder != nil
+}
+
+// endPonder ends the ponder search before a search on pos. When the
+// expected reply was played the engine is sent ponderhit and the ponder
+// search becomes the search, otherwise it is stopped
+func (e *uciDriver) endPonder(pos *chess.Position) (uci.SearchResults, bool, error) {
+	p := e.ponder
+	if p.fen != pos.String() {
+		return uci.SearchResults{}, false, e.stopPonder()
+	}
+	e.ponder = nil
+	if err := e.proc.send("ponderhit"); err != nil {
+		return uci.SearchResults{}, false, err
+	}
+	if err := waitPonder(p, searchLimit(p.cmdGo)); err != nil {
+		return uci.SearchResults{}, false, err
+	}
+	e.lines = p.lines
+	return p.results, true, p.err
+}
+
+// stopPonder stops the ponder search, if any, and discards its result
+func (e *uciDriver) stopPonder() error {
+	p := e.ponder
+	if p == nil {
+		return nil
+	}
+	e.ponder = nil
+	if err := e.proc.send("stop"); err != nil {
+		return err
+	}
+	if err := waitPonder(p, engineTimeout); err != nil {
+		return err
+	}
+	if p.err == errEngineExited {
+		return p.err
+	}
+	return nil
+}
+
+// waitPonder waits for the best move of a ponder search
+func waitPonder(p *ponderSearch, timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-p.done:
+		return nil
+	case <-timer.C:
+		return errEngineTimeout
+	}
+}
+
+// searchCmd returns the go command for the limits of an engine config
+func searchCmd(cfg *UCIEngine) uci.CmdGo {
+	cmdGo := uci.CmdGo{Depth: cfg.Depth}
+	cmdGo.MoveTime = cfg.MoveTime * time.Millisecond
+	// If SearchMoves is specified, include it
+	if cfg.SearchMoves != "" {
+		cmdGo.SearchMoves = searchMoves(cfg.SearchMoves)
+	}
+	return cmdGo
+}
+
+// ponderReply returns the reply the CPU opponent expects in the current
+// position, nil when it does not ponder
+func ponderReply(gs *GameState) (Engine, *UCIEngine, *chess.Move) {
+	eng, cfg, human, ok := cpuOpponent(gs)
+	if !ok || !cfg.Ponder || gs.Ponder == nil || gs.Puzzle != nil {
+		return nil, nil, nil
+	}
+	if gs.Game.Outcome() != chess.NoOutcome || gs.Game.Position().Turn() != human {
+		return nil, nil, nil
+	}
+	reply := findMove(gs.Game.Position(), gs.Ponder)
+	if reply == nil {
+		return nil, nil, nil
+	}
+	return eng, cfg, reply
+}
+
+// StartPonder lets the CPU opponent ponder on its expected reply while the
+// human is to move
+func StartPonder(gs *GameState) {
+	eng, cfg, reply := ponderReply(gs)
+	if reply == nil {
+		return
+	}
+	eng.Ponder(gs.Game.Position(), reply, searchCmd(cfg))
+}
+
+// PonderMove returns the reply the CPU opponent is pondering on, nil when
+// it is not pondering
+func PonderMove(gs *GameState) *chess.Move {
+	eng, _, reply := ponderReply(gs)
+	if reply == nil || !eng.Pondering() {
+		return nil
+	}
+	return reply
+}
@@ -0,0 +1,105 @@
+package uchess
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// ponderScript expects 2. Nf3 after 1. e4 c5 and answers it with d6, and
+// 2. Nc3 with Nc6
+const ponderScript = `
+position ` + e4Key + `
+bestmove c7c5 ponder g1f3
+
+position rnbqkbnr/pp1ppppp/8/2p5/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq
+info depth 10 score cp 15 pv d7d6
+bestmove d7d6 ponder d2d4
+
+position rnbqkbnr/pp1ppppp/8/2p5/4P3/2N5/PPPP1PPP/R1BQKBNR b KQkq
+info depth 10 score cp 10 pv b8c6
+bestmove b8c6 ponder g1f3
+`
+
+// commandsAfter returns the commands of a command log following the first
+// command starting with prefix
+func commandsAfter(t *testing.T, log, prefix string) []string {
+	t.Helper()
+	data, err := ioutil.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmds := strings.Split(strings.TrimSpace(string(data)), "\n")
+	for i, cmd := range cmds {
+		if strings.HasPrefix(cmd, prefix) {
+			return cmds[i+1:]
+		}
+	}
+	t.Fatalf("commands %q, want one starting with %q", cmds, prefix)
+	return nil
+}
+
+func TestPonder(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		reply string   // Human's reply to 1... c5
+		want  string   // Engine's answer
+		cmds  []string // Commands after go ponder, by prefix
+	}{
+		// The ponder search becomes the search
+		{"ponderhit", "Nf3", "d7d6", []string{"ponderhit"}},
+		// The ponder search is stopped and its best move discarded
+		{"ponder miss", "Nc3", "b8c6", []string{"stop", "position fen rnbqkbnr/pp1ppppp/8/2p5/4P3/2N5/PPPP1PPP/R1BQKBNR b", "go "}},
+	} {
+		cfg := mockEngine(t, "mock", ponderScript)
+		log := mockLog(t, cfg)
+		cfg.Ponder = true
+		gs := newTestState(t, testConfig("human", "cpu"), cfg, cfg, cfg)
+		gs.Game.MoveStr("e4")
+		EngMove(gs)
+
+		StartPonder(gs)
+		if move := PonderMove(gs); move == nil || move.String() != "g1f3" {
+			t.Fatalf("%v: PonderMove() = %v, want g1f3", tc.name, move)
+		}
+		// Pondering again on the same reply keeps the search
+		StartPonder(gs)
+		if err := gs.Game.MoveStr(tc.reply); err != nil {
+			t.Fatal(err)
+		}
+		EngMove(gs)
+		if got := playedMove(gs.Game); got != tc.want {
+			t.Errorf("%v: engine played %v, want %v", tc.name, got, tc.want)
+		}
+		if gs.UCI.UciBlack.Pondering() {
+			t.Errorf("%v: ponder search still running after the move", tc.name)
+		}
+
+		// The engine ponders on the position after the expected reply
+		if data, _ := ioutil.ReadFile(log); !strings.Contains(string(data), " moves g1f3\ngo ponder") {
+			t.Errorf("%v: commands %q, want pondering on g1f3", tc.name, data)
+		}
+		cmds := commandsAfter(t, log, "go ponder")
+		if len(cmds) != len(tc.cmds) {
+			t.Errorf("%v: commands %q after go ponder, want %q", tc.name, cmds, tc.cmds)
+			continue
+		}
+		for i, cmd := range cmds {
+			if !strings.HasPrefix(cmd, tc.cmds[i]) {
+				t.Errorf("%v: commands %q after go ponder, want %q", tc.name, cmds, tc.cmds)
+				break
+			}
+		}
+	}
+}
+
+func TestPonderMoveWithoutPonder(t *testing.T) {
+	cfg := mockEngine(t, "mock", ponderScript)
+	gs := newTestState(t, testConfig("human", "cpu"), cfg, cfg, cfg)
+	gs.Game.MoveStr("e4")
+	EngMove(gs)
+	StartPonder(gs)
+	if move := PonderMove(gs); move != nil {
+		t.Errorf("PonderMove() = %v, want nil for an engine which does not ponder", move)
+	}
+}
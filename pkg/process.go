@@ -0,0 +1,181 @@
+package uchess
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// engineTimeout is how long the engine may take to answer uci or isready
+	engineTimeout = 30 * time.Second
+	// quitTimeout is how long the engine may take to exit after quit
+	quitTimeout = time.Second
+	// lineBuffer is the number of output lines buffered while nobody reads
+	lineBuffer = 1024
+	// tcpScheme prefixes the address of an engine served over TCP
+	tcpScheme = "tcp://"
+)
+
+var (
+	// errEngineExited is returned when the engine process has exited
+	errEngineExited = errors.New("engine: process exited")
+	// errEngineTimeout is returned when the engine did not answer in time
+	errEngineTimeout = errors.New("engine: no response")
+)
+
+// engineProcess is a running engine talking over its stdin and stdout, or
+// over a TCP connection. The output is read on a goroutine so that a dead or
+// hung engine is noticed instead of blocking uchess
+type engineProcess struct {
+	stdin io.WriteCloser
+	stop  func()      // Ends the process or closes the connection
+	lines chan string // Engine output, closed when the engine exits
+}
+
+// startProcess starts the engine of an engine config. Paths starting with
+// tcp:// connect to an engine served at host:port, others run the engine
+// binary with the configured arguments, environment and working directory
+func startProcess(cfg *UCIEngine) (*engineProcess, error) {
+	if strings.HasPrefix(cfg.Path, tcpScheme) {
+		return dialProcess(strings.TrimPrefix(cfg.Path, tcpScheme))
+	}
+	path := cfg.Path
+	// Relative paths are relative to the working directory of the engine.
+	// The path is made absolute since the command runs in that directory
+	if cfg.WorkDir != "" && strings.ContainsRune(path, filepath.Separator) && !filepath.IsAbs(path) {
+		abs, err := filepath.Abs(filepath.Join(cfg.WorkDir, path))
+		if err != nil {
+			return nil, fmt.Errorf("engine: executable not found at path %v: %w", cfg.Path, err)
+		}
+		path = abs
+	}
+	path, err := exec.LookPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("engine: executable not found at path %v: %w", cfg.Path, err)
+	}
+	cmd := exec.Command(path, cfg.Args...)
+	cmd.Dir = cfg.WorkDir
+	if len(cfg.Env) > 0 {
+		cmd.Env = append(os.Environ(), envList(cfg.Env)...)
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	stop := func() {
+		stdin.Close()
+		cmd.Process.Kill()
+	}
+	p := &engineProcess{stdin, stop, make(chan string, lineBuffer)}
+	go p.read(stdout, func() { cmd.Wait() })
+	return p, nil
+}
+
+// dialProcess connects to an engine served over TCP
+func dialProcess(addr string) (*engineProcess, error) {
+	conn, err := net.DialTimeout("tcp", addr, engineTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("engine: cannot connect to %v: %w", addr, err)
+	}
+	stop := func() { conn.Close() }
+	p := &engineProcess{conn, stop, make(chan string, lineBuffer)}
+	go p.read(conn, func() {})
+	return p, nil
+}
+
+// read passes the engine output to lines until the engine exits, then
+// closes lines and calls done
+func (p *engineProcess) read(r io.Reader, done func()) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		p.lines <- strings.TrimRight(scanner.Text(), "\r")
+	}
+	close(p.lines)
+	done()
+}
+
+// envList returns environment variables as KEY=value pairs sorted by key
+func envList(env map[string]string) []string {
+	list := make([]string, 0, len(env))
+	for k, v := range env {
+		list = append(list, k+"="+v)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// send writes a command line to the engine
+func (p *engineProcess) send(line string) error {
+	if _, err := fmt.Fprintln(p.stdin, line); err != nil {
+		return errEngineExited
+	}
+	return nil
+}
+
+// drain discards output left over from earlier commands
+func (p *engineProcess) drain() {
+	for {
+		select {
+		case _, ok := <-p.lines:
+			if !ok {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+// readUntil passes engine output to f until f returns true. A timeout of
+// zero waits for as long as the engine runs
+func (p *engineProcess) readUntil(timeout time.Duration, f func(line string) bool) error {
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	for {
+		select {
+		case line, ok := <-p.lines:
+			if !ok {
+				return errEngineExited
+			}
+			if f(line) {
+				return nil
+			}
+		case <-expired:
+			return errEngineTimeout
+		}
+	}
+}
+
+// kill ends the engine process without asking
+func (p *engineProcess) kill() {
+	p.stop()
+}
+
+// quit asks the engine to exit and kills it when it does not
+func (p *engineProcess) quit() {
+	p.send("quit")
+	p.readUntil(quitTimeout, func(string) bool { return false })
+	p.kill()
+}
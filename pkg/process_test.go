@@ -0,0 +1,157 @@
+package uchess
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/uci"
+	"github.com/tmountain/uchess/pkg/mockuci"
+)
+
+// processScript answers the start position with e4
+const processScript = "id name Mock\nposition " + startKey + "\nbestmove e2e4\n"
+
+// copyExecutable copies the test binary to file so it can be started from
+// another path
+func copyExecutable(t *testing.T, file string) {
+	t.Helper()
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := os.Open(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	dst, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+	if _, err := io.Copy(dst, src); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// searchStart starts the engine of the config and returns its move in the
+// start position
+func searchStart(t *testing.T, cfg *UCIEngine) string {
+	t.Helper()
+	eng, err := StartEngine(cfg, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer eng.Close()
+	res, err := eng.Search(chess.StartingPosition(), uci.CmdGo{Depth: 1})
+	if err != nil || res.BestMove == nil {
+		t.Fatalf("Search() = %v, %v", res.BestMove, err)
+	}
+	return res.BestMove.String()
+}
+
+func TestStartProcess(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Relative work dirs are relative to the directory uchess runs in
+	base := t.TempDir()
+	if err := os.Chdir(base); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	rel := "work"
+	dir := filepath.Join(base, rel)
+	copyExecutable(t, filepath.Join(dir, "bin", "eng"))
+
+	for _, tc := range []struct {
+		name    string
+		path    string // Empty for the test binary
+		args    []string
+		workDir string
+		wantDir string
+	}{
+		{"args", "", []string{"-threads", "2"}, "", base},
+		{"absolute work dir", "", nil, dir, dir},
+		{"relative work dir", "", nil, rel, dir},
+		// Relative paths are relative to the working directory
+		{"relative path", filepath.Join("bin", "eng"), nil, dir, dir},
+		{"relative path and work dir", filepath.Join("bin", "eng"), nil, rel, dir},
+	} {
+		cfg := mockEngine(t, "mock", processScript)
+		probe := filepath.Join(t.TempDir(), "probe")
+		cfg.Env[mockProbeEnv] = probe
+		cfg.Args, cfg.WorkDir = tc.args, tc.workDir
+		if tc.path != "" {
+			cfg.Path = tc.path
+		}
+		if move := searchStart(t, cfg); move != "e2e4" {
+			t.Errorf("%v: engine played %v, want e2e4", tc.name, move)
+		}
+
+		// The environment carries the probe file
+		data, err := ioutil.ReadFile(probe)
+		if err != nil {
+			t.Errorf("%v: %v", tc.name, err)
+			continue
+		}
+		lines := strings.Split(string(data), "\n")
+		if args := strings.Join(tc.args, " "); lines[0] != args {
+			t.Errorf("%v: engine arguments %q, want %q", tc.name, lines[0], args)
+		}
+		got, _ := filepath.EvalSymlinks(lines[1])
+		want, _ := filepath.EvalSymlinks(tc.wantDir)
+		if got != want {
+			t.Errorf("%v: engine ran in %v, want %v", tc.name, lines[1], tc.wantDir)
+		}
+	}
+}
+
+func TestStartProcessNotFound(t *testing.T) {
+	cfg := &UCIEngine{Name: "missing", Path: filepath.Join("bin", "missing"), WorkDir: t.TempDir()}
+	if _, err := startProcess(cfg); err == nil || !strings.Contains(err.Error(), "executable not found") {
+		t.Errorf("startProcess() = %v, want executable not found", err)
+	}
+}
+
+func TestDialProcess(t *testing.T) {
+	script, err := mockuci.Parse(strings.NewReader(processScript))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	// Serve one engine over the first connection
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		script.Run(conn, conn, nil)
+	}()
+
+	cfg := &UCIEngine{Name: "remote", Path: tcpScheme + ln.Addr().String(), Depth: 1}
+	if move := searchStart(t, cfg); move != "e2e4" {
+		t.Errorf("remote engine played %v, want e2e4", move)
+	}
+
+	// Nothing listens once the listener is closed
+	ln.Close()
+	if _, err := startProcess(cfg); err == nil || !strings.Contains(err.Error(), "cannot connect") {
+		t.Errorf("startProcess() = %v, want cannot connect", err)
+	}
+}
@@ -0,0 +1,309 @@
+package uchess
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+const (
+	// puzzleStatsFile stores the puzzle rating and streak in the app dir
+	puzzleStatsFile = "puzzles.json"
+	// defaultPuzzleRating is the rating of a new puzzle solver
+	defaultPuzzleRating = 1500
+	// puzzleK is the K-factor of the puzzle rating updates
+	puzzleK = 32
+)
+
+// Puzzle is a tactics puzzle in the Lichess format. The FEN is the position
+// before the opponent's move, which is the first move of the solution
+type Puzzle struct {
+	ID     string
+	FEN    string
+	Moves  []string // Solution in UCI notation
+	Rating int
+	Themes []string
+}
+
+// PuzzleStats holds the solver's rating and streak across sessions
+type PuzzleStats struct {
+	Rating     int            `json:"rating"`
+	Streak     int            `json:"streak"`
+	BestStreak int            `json:"bestStreak"`
+	Solved     int            `json:"solved"`
+	Failed     int            `json:"failed"`
+	Position   map[string]int `json:"position"` // Next puzzle index by file
+}
+
+// PuzzleState tracks the puzzle being solved
+type PuzzleState struct {
+	File    string
+	Puzzles []Puzzle
+	Index   int
+	Ply     int  // Index of the next solution move
+	Rated   bool // The result of the current puzzle was recorded
+	Done    bool // The current puzzle is finished
+	Stats   PuzzleStats
+}
+
+// LoadPuzzles reads puzzles from a Lichess puzzle CSV file
+// (PuzzleId,FEN,Moves,Rating,...). The header line is optional
+func LoadPuzzles(file string) ([]Puzzle, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	puzzles := make([]Puzzle, 0)
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if len(record) < 3 || record[0] == "PuzzleId" {
+			continue
+		}
+		p := Puzzle{ID: record[0], FEN: record[1], Moves: strings.Fields(record[2])}
+		if len(record) > 3 {
+			p.Rating, _ = strconv.Atoi(record[3])
+		}
+		if len(record) > 7 {
+			p.Themes = strings.Fields(record[7])
+		}
+		if len(p.Moves) < 2 {
+			continue
+		}
+		puzzles = append(puzzles, p)
+	}
+	if len(puzzles) == 0 {
+		return nil, errors.New("puzzles: no puzzles found")
+	}
+	return puzzles, nil
+}
+
+// puzzleStatsPath returns the location of the puzzle stats
+func puzzleStatsPath() string {
+	return filepath.Join(AppDir(), puzzleStatsFile)
+}
+
+// LoadPuzzleStats reads the puzzle stats from the app dir, a new solver
+// starts at the default rating
+func LoadPuzzleStats() PuzzleStats {
+	stats := PuzzleStats{Rating: defaultPuzzleRating}
+	if data, err := ioutil.ReadFile(puzzleStatsPath()); err == nil {
+		json.Unmarshal(data, &stats)
+	}
+	if stats.Position == nil {
+		stats.Position = make(map[string]int)
+	}
+	return stats
+}
+
+// savePuzzleStats writes the puzzle stats to the app dir
+func savePuzzleStats(stats PuzzleStats) error {
+	if err := os.MkdirAll(AppDir(), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(&stats, "", "    ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(puzzleStatsPath(), data, 0644)
+}
+
+// NewPuzzleState starts a puzzle session on a file, resuming after the
+// last puzzle played from it
+func NewPuzzleState(file string, puzzles []Puzzle) *PuzzleState {
+	ps := &PuzzleState{File: file, Puzzles: puzzles, Stats: LoadPuzzleStats()}
+	if abs, err := filepath.Abs(file); err == nil {
+		ps.File = abs
+	}
+	ps.Index = ps.Stats.Position[ps.File] % len(puzzles)
+	return ps
+}
+
+// Current returns the puzzle being solved
+func (ps *PuzzleState) Current() Puzzle {
+	return ps.Puzzles[ps.Index]
+}
+
+// String returns the puzzle status line
+func (ps *PuzzleState) String() string {
+	p := ps.Current()
+	return fmt.Sprintf("Puzzle %v/%v (%v) | Rating %v | Streak %v",
+		ps.Index+1, len(ps.Puzzles), p.Rating, ps.Stats.Rating, ps.Stats.Streak)
+}
+
+// record rates the result of the current puzzle once
+func (ps *PuzzleState) record(solved bool) int {
+	if ps.Rated {
+		return 0
+	}
+	ps.Rated = true
+	score := 0.0
+	if solved {
+		score = 1
+		ps.Stats.Solved++
+		ps.Stats.Streak++
+		if ps.Stats.Streak > ps.Stats.BestStreak {
+			ps.Stats.BestStreak = ps.Stats.Streak
+		}
+	} else {
+		ps.Stats.Failed++
+		ps.Stats.Streak = 0
+	}
+	// Puzzles without a rating count as evenly matched
+	rating := ps.Current().Rating
+	if rating == 0 {
+		rating = ps.Stats.Rating
+	}
+	expected := 1 / (1 + math.Pow(10, float64(rating-ps.Stats.Rating)/400))
+	delta := int(math.Round(puzzleK * (score - expected)))
+	ps.Stats.Rating += delta
+	savePuzzleStats(ps.Stats)
+	return delta
+}
+
+// uciMove decodes a move in UCI notation in the given position
+func uciMove(pos *chess.Position, s string) *chess.Move {
+	move, err := chess.UCINotation{}.Decode(pos, s)
+	if err != nil {
+		return nil
+	}
+	return findMove(pos, move)
+}
+
+// StartPuzzle sets up the current puzzle and plays the opponent's first move
+func StartPuzzle(gs *GameState) string {
+	ps := gs.Puzzle
+	p := ps.Current()
+	ps.Ply, ps.Rated, ps.Done = 0, false, false
+	gs.Hint = nil
+	gs.HintPV = nil
+	clearGameData(gs)
+
+	game, err := NewGameFEN(p.FEN, false)
+	if err != nil {
+		ps.Done = true
+		return fmt.Sprintf("\u26A0 Puzzle %v has an invalid FEN, type next.", p.ID)
+	}
+	StartGame(gs, game)
+	if msg := playPuzzleReply(gs); msg != "" {
+		return msg
+	}
+	return fmt.Sprintf("%v to move. Find the best move.%v", gs.Game.Position().Turn().Name(), strings.Repeat(" ", 40))
+}
+
+// playPuzzleReply plays the opponent's next solution move
+func playPuzzleReply(gs *GameState) string {
+	ps := gs.Puzzle
+	move := uciMove(gs.Game.Position(), ps.Current().Moves[ps.Ply])
+	if move == nil || gs.Game.Move(move) != nil {
+		ps.Done = true
+		return fmt.Sprintf("\u26A0 Puzzle %v has an invalid solution, type next.", ps.Current().ID)
+	}
+	ps.Ply++
+	return ""
+}
+
+// nextPuzzle moves on to the next puzzle. Skipping an unfinished puzzle
+// counts as a failure
+func nextPuzzle(gs *GameState) string {
+	ps := gs.Puzzle
+	if !ps.Done {
+		ps.record(false)
+	}
+	ps.Index = (ps.Index + 1) % len(ps.Puzzles)
+	ps.Stats.Position[ps.File] = ps.Index
+	savePuzzleStats(ps.Stats)
+	return StartPuzzle(gs)
+}
+
+// puzzleHint highlights the next solution move. Puzzles solved with a hint
+// do not count as solved
+func puzzleHint(gs *GameState) string {
+	ps := gs.Puzzle
+	if ps.Done {
+		return "Puzzle finished, type next." + strings.Repeat(" ", 40)
+	}
+	gs.Hint = uciMove(gs.Game.Position(), ps.Current().Moves[ps.Ply])
+	if delta := ps.record(false); delta != 0 {
+		return fmt.Sprintf("Hint shown. Rating %v (%+d)%v", ps.Stats.Rating, delta, strings.Repeat(" ", 40))
+	}
+	return strings.Repeat(" ", 80)
+}
+
+// checkPuzzleMove compares the user's move against the solution. Any move
+// delivering mate is accepted in place of the solution move
+func checkPuzzleMove(gs *GameState, cmd string) string {
+	ps := gs.Puzzle
+	if ps.Done {
+		return "Puzzle finished, type next." + strings.Repeat(" ", 40)
+	}
+	pos := gs.Game.Position()
+	move, err := chess.AlgebraicNotation{}.Decode(pos, cmd)
+	if err != nil {
+		return "\u26A0 Illegal. Try again."
+	}
+
+	solution := uciMove(pos, ps.Current().Moves[ps.Ply])
+	isSolution := solution != nil && move.S1() == solution.S1() && move.S2() == solution.S2() && move.Promo() == solution.Promo()
+	isMate := pos.Update(move).Status() == chess.Checkmate
+	if !isSolution && !isMate {
+		delta := ps.record(false)
+		return fmt.Sprintf("\u2717 %v is not it. Rating %v (%+d), try again or type next.",
+			chess.AlgebraicNotation{}.Encode(pos, move), ps.Stats.Rating, delta)
+	}
+
+	gs.Game.Move(move)
+	ps.Ply++
+	if isMate || ps.Ply >= len(ps.Current().Moves) {
+		ps.Done = true
+		if ps.Rated {
+			return "\u2713 Solved, type next." + strings.Repeat(" ", 40)
+		}
+		delta := ps.record(true)
+		return fmt.Sprintf("\u2713 Solved! Rating %v (%+d), type next.%v", ps.Stats.Rating, delta, strings.Repeat(" ", 20))
+	}
+	if msg := playPuzzleReply(gs); msg != "" {
+		return msg
+	}
+	return "\u2713 Correct, keep going." + strings.Repeat(" ", 40)
+}
+
+// puzzleCmd handles the commands which behave differently in puzzle mode.
+// The bool result is false for commands left to the regular handler
+func puzzleCmd(gs *GameState, verb, cmd string) (string, bool) {
+	switch verb {
+	case "hint":
+		return puzzleHint(gs), true
+	case "next":
+		return nextPuzzle(gs), true
+	case "reset":
+		// Retrying does not give a second chance at the rating
+		rated := gs.Puzzle.Rated
+		msg := StartPuzzle(gs)
+		gs.Puzzle.Rated = rated
+		return msg, true
+	case "back", "load", "resign", "analyze-game", "blundercheck", "chess960", "newgame":
+		return "\u26A0 Not available in puzzle mode.", true
+	case "save", "mark", "arrow", "unmark", "image", "fen", "options":
+		return "", false
+	}
+	return checkPuzzleMove(gs, cmd), true
+}
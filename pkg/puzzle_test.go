@@ -0,0 +1,125 @@
+package uchess
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// puzzleState starts a puzzle session on testdata/puzzles.csv at the
+// puzzle with the ID, rated 1500 without a streak
+func puzzleState(t *testing.T, id string) *GameState {
+	t.Helper()
+	puzzles, err := LoadPuzzles(filepath.Join("testdata", "puzzles.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := &GameState{Config: testConfig("human", "human")}
+	gs.Puzzle = NewPuzzleState("puzzles.csv", puzzles)
+	gs.Puzzle.Stats = PuzzleStats{Rating: defaultPuzzleRating, Position: map[string]int{}}
+	for i, p := range puzzles {
+		if p.ID == id {
+			gs.Puzzle.Index = i
+		}
+	}
+	StartPuzzle(gs)
+	return gs
+}
+
+func TestLoadPuzzles(t *testing.T) {
+	puzzles, err := LoadPuzzles(filepath.Join("testdata", "puzzles.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The header and puzzles without a reply to the opponent are skipped
+	if len(puzzles) != 2 {
+		t.Fatalf("LoadPuzzles() = %v puzzles, want 2", len(puzzles))
+	}
+	p := puzzles[0]
+	if p.ID != "00sHx" || len(p.Moves) != 4 || p.Rating != 1760 || strings.Join(p.Themes, " ") != "mate mateIn2 middlegame short" {
+		t.Errorf("puzzle %+v, want 00sHx with its moves, rating and themes", p)
+	}
+
+	file := filepath.Join(t.TempDir(), "empty.csv")
+	if err := ioutil.WriteFile(file, []byte("PuzzleId,FEN,Moves\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPuzzles(file); err == nil {
+		t.Error("LoadPuzzles() succeeded, want an error for a file without puzzles")
+	}
+}
+
+func TestCheckPuzzleMove(t *testing.T) {
+	for _, tc := range []struct {
+		id     string
+		moves  []string
+		want   string // Start of the message after the last move
+		done   bool
+		rating int
+	}{
+		{"00sHx", []string{"Be6+"}, "\u2713 Correct, keep going.", false, 1500},
+		// The solution goes on after the opponent's reply
+		{"00sHx", []string{"Be6+", "Qf8#"}, "\u2713 Solved! Rating 1526 (+26)", true, 1526},
+		{"00sHx", []string{"Qxg7"}, "\u2717 Qxg7 is not it. Rating 1494 (-6)", false, 1494},
+		{"00sHx", []string{"Qg9"}, "\u26A0 Illegal.", false, 1500},
+		// A second try is not rated again
+		{"00sHx", []string{"Qxg7", "Be6+", "Qf8#"}, "\u2713 Solved, type next.", true, 1494},
+		// Any mate solves the puzzle
+		{"backrank", []string{"Rb8#"}, "\u2713 Solved! Rating 1516 (+16)", true, 1516},
+		{"backrank", []string{"Ra8#", "Rb8"}, "Puzzle finished, type next.", true, 1516},
+	} {
+		gs := puzzleState(t, tc.id)
+		msg := ""
+		for _, m := range tc.moves {
+			msg = checkPuzzleMove(gs, m)
+		}
+		ps := gs.Puzzle
+		if !strings.HasPrefix(msg, tc.want) || ps.Done != tc.done || ps.Stats.Rating != tc.rating {
+			t.Errorf("%v %v: checkPuzzleMove() = %q, done %v, rating %v, want %q, %v, %v",
+				tc.id, tc.moves, msg, ps.Done, ps.Stats.Rating, tc.want, tc.done, tc.rating)
+		}
+	}
+}
+
+func TestPuzzleHint(t *testing.T) {
+	gs := puzzleState(t, "00sHx")
+	// The opponent's first move has been played
+	if got := playedMove(gs.Game); got != "e8d7" {
+		t.Fatalf("puzzle starts after %v, want e8d7", got)
+	}
+	if msg := puzzleHint(gs); !strings.HasPrefix(msg, "Hint shown. Rating 1494 (-6)") {
+		t.Errorf("puzzleHint() = %q, want the rating loss", msg)
+	}
+	if gs.Hint == nil || gs.Hint.String() != "a2e6" {
+		t.Errorf("hint %v, want a2e6", gs.Hint)
+	}
+	checkPuzzleMove(gs, "Be6+")
+	if msg := checkPuzzleMove(gs, "Qf8#"); !strings.HasPrefix(msg, "\u2713 Solved, type next.") {
+		t.Errorf("checkPuzzleMove() = %q, want an unrated solve", msg)
+	}
+	if s := gs.Puzzle.Stats; s.Solved != 0 || s.Failed != 1 || s.Streak != 0 {
+		t.Errorf("stats %+v, want one failure", s)
+	}
+}
+
+func TestNextPuzzle(t *testing.T) {
+	gs := puzzleState(t, "backrank")
+	gs.Puzzle.Stats.Streak = 3
+	// Skipping an unfinished puzzle fails it, and the session wraps around
+	nextPuzzle(gs)
+	ps := gs.Puzzle
+	if ps.Index != 0 || ps.Stats.Streak != 0 || ps.Stats.Failed != 1 || ps.Stats.Position[ps.File] != 0 {
+		t.Errorf("after next: index %v, stats %+v, want puzzle 1 after a failure", ps.Index, ps.Stats)
+	}
+	// The position is kept for the next session on the file
+	if stats := LoadPuzzleStats(); stats.Failed != 1 {
+		t.Errorf("saved stats %+v, want the failure", stats)
+	}
+	if msg, ok := puzzleCmd(gs, "back", "back"); !ok || !strings.Contains(msg, "Not available") {
+		t.Errorf("puzzleCmd(back) = %q, %v, want it refused", msg, ok)
+	}
+	if _, ok := puzzleCmd(gs, "save", "save"); ok {
+		t.Error("puzzleCmd(save) handled, want it left to the regular commands")
+	}
+}
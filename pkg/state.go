@@ -1,20 +1,28 @@
 package uchess
 
 import (
-	"github.com/gdamore/tcell/v2"
 	"github.com/notnil/chess"
 )
 
 // GameState encapsulates everything needed to run the game
 type GameState struct {
-	S          tcell.Screen // Screen
-	Input      *Input       // Input
-	Game       *chess.Game  // Chess Board
-	UCI        UCIState     // UCI State
-	Config     Config       // Global Config
-	Theme      Theme        // Theme
-	Score      int          // Score in centipawns
-	CheckWhite bool         // White is in check
-	CheckBlack bool         // Black is in check
-	Hint       *chess.Move  // Hint when available
+	Game          *chess.Game          // Chess Board
+	StartFEN      string               // Starting position of the game
+	UCI           UCIState             // UCI State
+	Config        Config               // Global Config
+	Score         int                  // Score in centipawns
+	CheckWhite    bool                 // White is in check
+	CheckBlack    bool                 // Black is in check
+	Hint          *chess.Move          // Hint when available
+	HintPV        []*chess.Move        // Hint principal variation
+	Annotations   map[int][]Annotation // Square marks and arrows by ply
+	PendingMove   *chess.Move          // Move held back by the blunder check
+	BlunderChecks int                  // Number of times the blunder check fired this game
+	BookPlies     map[int]bool         // Plies played from the opening book
+	Puzzle        *PuzzleState         // Puzzle trainer state, nil outside of puzzle mode
+	Reviewing     bool                 // The game was opened from the game store for review
+	Recorded      bool                 // The finished game was added to the PGN database
+	Editor        *OptionsEditor       // Engine options editor, nil while closed
+	Ponder        *chess.Move          // Reply the CPU opponent expects and ponders on
+	notify        func(Event)          // Passes events to the subscribers of the controller
 }
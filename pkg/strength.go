@@ -0,0 +1,140 @@
+package uchess
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/uci"
+)
+
+const (
+	// weakMultiPV is the number of lines searched when uchess weakens an
+	// engine without native strength limiting
+	weakMultiPV = 4
+	// weakMaxElo is the rating at which uchess stops weakening the engine
+	weakMaxElo = 2600
+)
+
+// nativeElo returns a bool indicating whether the engine limits its own
+// strength through the UCI_LimitStrength and UCI_Elo options
+func nativeElo(options []EngineOption) bool {
+	_, limit := findOption(options, "UCI_LimitStrength")
+	_, elo := findOption(options, "UCI_Elo")
+	return limit && elo
+}
+
+// clampElo limits the rating to the range the engine declares for UCI_Elo
+func clampElo(options []EngineOption, elo int) int {
+	opt, _ := findOption(options, "UCI_Elo")
+	if min, err := strconv.Atoi(opt.Min); err == nil && elo < min {
+		return min
+	}
+	if max, err := strconv.Atoi(opt.Max); err == nil && elo > max {
+		return max
+	}
+	return elo
+}
+
+// strengthOptions returns the options limiting the engine to the configured
+// rating. Engines without native support search several lines instead so
+// that uchess can pick weaker moves
+func strengthOptions(options []EngineOption, cfg *UCIEngine) []Option {
+	if !cfg.LimitStrength {
+		return nil
+	}
+	if nativeElo(options) {
+		return []Option{
+			{"UCI_LimitStrength", "true"},
+			{"UCI_Elo", strconv.Itoa(clampElo(options, cfg.Elo))},
+		}
+	}
+	return []Option{{"MultiPV", strconv.Itoa(weakMultiPV)}}
+}
+
+// weakened returns a bool indicating whether uchess has to weaken the engine
+func weakened(eng Engine, cfg *UCIEngine) bool {
+	// An engine which fails to start fails the search as well
+	options, _ := eng.Options()
+	return cfg.LimitStrength && cfg.Elo < weakMaxElo && !nativeElo(options)
+}
+
+// weakDepth returns a randomized search depth for the rating, roughly one
+// ply per 200 points
+func weakDepth(elo int, rnd *rand.Rand) int {
+	depth := elo/200 - 3 + rnd.Intn(3) - 1
+	if depth < 1 {
+		return 1
+	}
+	return depth
+}
+
+// weakTemperature returns the sampling temperature in centipawns for the
+// rating. Lower ratings pick worse moves more often
+func weakTemperature(elo int) float64 {
+	if elo >= weakMaxElo {
+		return 0
+	}
+	return float64(weakMaxElo-elo) / 8
+}
+
+// sampleMove picks one of the searched lines with a probability falling off
+// exponentially with its score below the best line. Lines starting with an
+// illegal move are left out
+func sampleMove(pos *chess.Position, lines []uci.Info, elo int, rnd *rand.Rand) *chess.Move {
+	t := weakTemperature(elo)
+	best := math.MinInt32
+	moves := make([]*chess.Move, 0, len(lines))
+	scores := make([]int, 0, len(lines))
+	for _, info := range lines {
+		move := findMove(pos, info.PV[0])
+		if move == nil {
+			continue
+		}
+		cp := clampCP(scoreCP(info.Score))
+		if cp > best {
+			best = cp
+		}
+		moves = append(moves, move)
+		scores = append(scores, cp)
+	}
+
+	weights := make([]float64, 0, len(moves))
+	total := 0.0
+	for _, cp := range scores {
+		w := 0.0
+		if t > 0 {
+			w = math.Exp(float64(cp-best) / t)
+		} else if cp == best {
+			w = 1
+		}
+		weights = append(weights, w)
+		total += w
+	}
+	if total == 0 {
+		return nil
+	}
+
+	r := rnd.Float64() * total
+	for i, w := range weights {
+		if r < w {
+			return moves[i]
+		}
+		r -= w
+	}
+	return moves[len(moves)-1]
+}
+
+// weakMove searches the position at a reduced depth and samples one of the
+// best lines. Nil is returned if the search fails
+func weakMove(eng Engine, cfg *UCIEngine, pos *chess.Position) *chess.Move {
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	cmdGo := uci.CmdGo{Depth: weakDepth(cfg.Elo, rnd)}
+	cmdGo.MoveTime = cfg.MoveTime * time.Millisecond
+	if _, err := eng.Search(pos, cmdGo); err != nil {
+		return nil
+	}
+	return sampleMove(pos, eng.Lines(), cfg.Elo, rnd)
+}
@@ -0,0 +1,206 @@
+package uchess
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/uci"
+)
+
+// nativeHeader declares the UCI strength options with the range of Stockfish
+const nativeHeader = "id name Native\n" +
+	"option name UCI_LimitStrength type check default false\n" +
+	"option name UCI_Elo type spin default 1350 min 1350 max 2850\n"
+
+// weakScript answers the start position with four lines for uchess to
+// sample from
+const weakScript = `
+position ` + startKey + `
+info depth 3 multipv 1 score cp 40 pv e2e4
+info depth 3 multipv 2 score cp 30 pv d2d4
+info depth 3 multipv 3 score cp -60 pv b1a3
+info depth 3 multipv 4 score cp -250 pv g2g4
+bestmove e2e4
+`
+
+// parseOptions parses the option declarations of a script header
+func parseOptions(t *testing.T, header string) []EngineOption {
+	t.Helper()
+	options := make([]EngineOption, 0)
+	for _, line := range strings.Split(header, "\n") {
+		if strings.HasPrefix(line, "option ") {
+			opt, err := ParseOption(line)
+			if err != nil {
+				t.Fatal(err)
+			}
+			options = append(options, opt)
+		}
+	}
+	return options
+}
+
+// infoLine returns a search line with the score and the first move of the pv
+func infoLine(t *testing.T, cp int, move string) uci.Info {
+	t.Helper()
+	m, err := chess.UCINotation{}.Decode(nil, move)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return uci.Info{Score: uci.Score{CP: cp}, PV: []*chess.Move{m}}
+}
+
+func TestStrengthOptions(t *testing.T) {
+	native := parseOptions(t, nativeHeader)
+	for _, tc := range []struct {
+		name    string
+		options []EngineOption
+		limit   bool
+		elo     int
+		want    []Option
+	}{
+		{"unlimited", native, false, 1500, nil},
+		{"native", native, true, 1500, []Option{{"UCI_LimitStrength", "true"}, {"UCI_Elo", "1500"}}},
+		{"below the minimum", native, true, 800, []Option{{"UCI_LimitStrength", "true"}, {"UCI_Elo", "1350"}}},
+		{"above the maximum", native, true, 3200, []Option{{"UCI_LimitStrength", "true"}, {"UCI_Elo", "2850"}}},
+		{"without UCI_Elo", native[:1], true, 1500, []Option{{"MultiPV", "4"}}},
+		{"without options", nil, true, 1500, []Option{{"MultiPV", "4"}}},
+	} {
+		cfg := &UCIEngine{LimitStrength: tc.limit, Elo: tc.elo}
+		got := strengthOptions(tc.options, cfg)
+		if len(got) != len(tc.want) {
+			t.Errorf("%v: strengthOptions() = %v, want %v", tc.name, got, tc.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("%v: strengthOptions() = %v, want %v", tc.name, got, tc.want)
+			}
+		}
+	}
+}
+
+func TestWeakDepth(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, tc := range []struct {
+		elo      int
+		min, max int
+	}{
+		{400, 1, 1},
+		{1000, 1, 3},
+		{1600, 4, 6},
+		{2400, 8, 10},
+	} {
+		seen := make(map[int]bool)
+		for i := 0; i < 100; i++ {
+			depth := weakDepth(tc.elo, rnd)
+			if depth < tc.min || depth > tc.max {
+				t.Fatalf("weakDepth(%v) = %v, want %v to %v", tc.elo, depth, tc.min, tc.max)
+			}
+			seen[depth] = true
+		}
+		if len(seen) != tc.max-tc.min+1 {
+			t.Errorf("weakDepth(%v) returned %v, want every depth from %v to %v", tc.elo, seen, tc.min, tc.max)
+		}
+	}
+}
+
+func TestWeakTemperature(t *testing.T) {
+	for _, tc := range []struct {
+		elo  int
+		want float64
+	}{
+		{weakMaxElo, 0},
+		{3000, 0},
+		{2200, 50},
+		{1000, 200},
+	} {
+		if got := weakTemperature(tc.elo); got != tc.want {
+			t.Errorf("weakTemperature(%v) = %v, want %v", tc.elo, got, tc.want)
+		}
+	}
+}
+
+func TestSampleMove(t *testing.T) {
+	pos := chess.StartingPosition()
+	lines := []uci.Info{
+		infoLine(t, 40, "e2e4"),
+		infoLine(t, 30, "d2d4"),
+		infoLine(t, -60, "b1a3"),
+		infoLine(t, -250, "g2g4"),
+		// Lines with illegal moves are never played
+		infoLine(t, 500, "e2e5"),
+	}
+	for _, tc := range []struct {
+		elo  int
+		want []string // Moves from most to least often played
+	}{
+		{weakMaxElo, []string{"e2e4"}},
+		{2400, []string{"e2e4", "d2d4", "b1a3"}},
+		{800, []string{"e2e4", "d2d4", "b1a3", "g2g4"}},
+	} {
+		rnd := rand.New(rand.NewSource(1))
+		counts := make(map[string]int)
+		for i := 0; i < 2000; i++ {
+			move := sampleMove(pos, lines, tc.elo, rnd)
+			if move == nil {
+				t.Fatalf("sampleMove(%v) = nil", tc.elo)
+			}
+			counts[move.String()]++
+		}
+		if len(counts) != len(tc.want) {
+			t.Errorf("sampleMove(%v) played %v, want %v", tc.elo, counts, tc.want)
+			continue
+		}
+		for i := 1; i < len(tc.want); i++ {
+			if counts[tc.want[i]] >= counts[tc.want[i-1]] {
+				t.Errorf("sampleMove(%v) played %v, want %v from most to least often", tc.elo, counts, tc.want)
+			}
+		}
+	}
+
+	if move := sampleMove(pos, lines[4:], 800, rand.New(rand.NewSource(1))); move != nil {
+		t.Errorf("sampleMove() = %v without legal lines, want nil", move)
+	}
+}
+
+func TestWeakMove(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		header   string
+		elo      int
+		weakened bool
+		want     string // Strength option read by the engine
+	}{
+		{"weak", mockHeader, 1200, true, "setoption name MultiPV value 4"},
+		{"full strength", mockHeader, weakMaxElo, false, "setoption name MultiPV value 4"},
+		{"native", nativeHeader, 1200, false, "setoption name UCI_Elo value 1350"},
+	} {
+		cfg := mockEngine(t, "mock", tc.header+weakScript)
+		log := mockLog(t, cfg)
+		cfg.LimitStrength, cfg.Elo = true, tc.elo
+		eng, err := StartEngine(cfg, Config{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer eng.Close()
+		if got := weakened(eng, cfg); got != tc.weakened {
+			t.Errorf("%v: weakened() = %v, want %v", tc.name, got, tc.weakened)
+		}
+		if tc.weakened {
+			move := weakMove(eng, cfg, chess.StartingPosition())
+			if move == nil || !strings.Contains("e2e4 d2d4 b1a3 g2g4", move.String()) {
+				t.Errorf("%v: weakMove() = %v, want one of the searched lines", tc.name, move)
+			}
+		}
+		data, err := ioutil.ReadFile(log)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), tc.want) {
+			t.Errorf("%v: engine read %q, want %q", tc.name, data, tc.want)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package uchess
+
+import (
+	"fmt"
+
+	"github.com/notnil/chess"
+)
+
+// supervise runs f on the engine, starting the engine first if needed.
+// When the engine exited or stopped responding, it is restarted and f runs
+// once more. The restart is kept as an event for the message line
+func (e *engineBase) supervise(f func() error) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.proc == nil {
+		if err := e.launch(); err != nil {
+			return err
+		}
+	}
+	err := f()
+	if err != errEngineExited && err != errEngineTimeout {
+		return err
+	}
+
+	cause := "crashed"
+	if err == errEngineTimeout {
+		cause = "stopped responding"
+	}
+	e.proc.kill()
+	if err := e.restart(); err != nil {
+		e.event = fmt.Sprintf("%v %v and could not be restarted", e.cfg.Name, cause)
+		return err
+	}
+	e.event = fmt.Sprintf("%v %v and was restarted", e.cfg.Name, cause)
+	return f()
+}
+
+// restart starts a new engine process and replays the options that were
+// sent to the old one
+func (e *engineBase) restart() error {
+	if err := e.start(); err != nil {
+		return err
+	}
+	for _, o := range e.sent {
+		if err := e.proto.sendOption(o.Name, o.Value); err != nil {
+			return err
+		}
+	}
+	return e.proto.newGame()
+}
+
+// Event returns the last restart of the engine and clears it. An empty
+// string is returned when the engine has not been restarted
+func (e *engineBase) Event() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	event := e.event
+	e.event = ""
+	return event
+}
+
+// EngineEvent returns a message for the first engine restart not reported
+// yet
+func EngineEvent(us UCIState) string {
+	for _, eng := range []Engine{us.UciWhite, us.UciBlack, us.UciHint} {
+		if event := eng.Event(); event != "" {
+			return "\u26A0 " + event + "."
+		}
+	}
+	return ""
+}
+
+// forfeit ends the game when the engine of the side to move crashed in a
+// CPU match
+func forfeit(game *chess.Game) {
+	game.Resign(game.Position().Turn())
+	game.AddTagPair("Termination", "abandoned")
+}
+
+// engineCrash forfeits a CPU match when the engine of the side to move was
+// restarted during its search. Interactive games carry on, the restart is
+// reported by EngineEvent
+func engineCrash(gs *GameState, eng Engine) (string, bool) {
+	if IsInteractive(gs.Config) {
+		return "", false
+	}
+	event := eng.Event()
+	if event == "" {
+		return "", false
+	}
+	side := gs.Game.Position().Turn()
+	forfeit(gs.Game)
+	return fmt.Sprintf("\u26A0 %v. %v forfeits.", event, side.Name()), true
+}
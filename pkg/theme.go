@@ -38,6 +38,10 @@ type Theme struct {
 	Emoji        tcell.Color `json:"emoji"`
 	Input        tcell.Color `json:"input"`
 	Advantage    tcell.Color `json:"advantage"`
+	MarkRed      tcell.Color `json:"markRed"`
+	MarkGreen    tcell.Color `json:"markGreen"`
+	MarkBlue     tcell.Color `json:"markBlue"`
+	MarkYellow   tcell.Color `json:"markYellow"`
 }
 
 // ThemeHex is used for dynamically coloring the UI
@@ -67,6 +71,10 @@ type ThemeHex struct {
 	Emoji        string `json:"emoji"`
 	Input        string `json:"input"`
 	Advantage    string `json:"advantage"`
+	MarkRed      string `json:"markRed"`
+	MarkGreen    string `json:"markGreen"`
+	MarkBlue     string `json:"markBlue"`
+	MarkYellow   string `json:"markYellow"`
 }
 
 // fmtHex returns a one character hex for the ColorDefault
@@ -108,6 +116,10 @@ func (t Theme) Hex() ThemeHex {
 		fmtHex(t.Emoji.Hex()),
 		fmtHex(t.Input.Hex()),
 		fmtHex(t.Advantage.Hex()),
+		fmtHex(t.MarkRed.Hex()),
+		fmtHex(t.MarkGreen.Hex()),
+		fmtHex(t.MarkBlue.Hex()),
+		fmtHex(t.MarkYellow.Hex()),
 	}
 }
 
@@ -139,6 +151,10 @@ func (t ThemeHex) Theme() Theme {
 		tcell.GetColor(t.Emoji),
 		tcell.GetColor(t.Input),
 		tcell.GetColor(t.Advantage),
+		tcell.GetColor(t.MarkRed),
+		tcell.GetColor(t.MarkGreen),
+		tcell.GetColor(t.MarkBlue),
+		tcell.GetColor(t.MarkYellow),
 	}
 }
 
@@ -182,4 +198,8 @@ var ThemeBasic = Theme{
 	tcell.ColorDefault, // Emoji
 	tcell.ColorDefault, // Input
 	tcell.Color247,     // Advantage
+	tcell.Color210,     // MarkRed
+	tcell.Color150,     // MarkGreen
+	tcell.Color117,     // MarkBlue
+	tcell.Color228,     // MarkYellow
 }
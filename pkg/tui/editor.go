@@ -0,0 +1,119 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	uchess "github.com/tmountain/uchess/pkg"
+)
+
+// editorWidth is the width of the options editor including the border
+const editorWidth = 60
+
+// editorKey handles a key press while the options editor is open
+func (ui *UI) editorKey(ev *tcell.EventKey) {
+	gs := ui.Ctrl.State
+	e := gs.Editor
+	if e.Editing {
+		switch ev.Key() {
+		case tcell.KeyEnter:
+			e.Apply()
+		case tcell.KeyEscape:
+			e.Cancel()
+		case tcell.KeyBackspace, tcell.KeyBackspace2:
+			if r := []rune(e.Buffer); len(r) > 0 {
+				e.Buffer = string(r[:len(r)-1])
+			}
+		case tcell.KeyRune:
+			e.Buffer += string(ev.Rune())
+		}
+		return
+	}
+
+	switch ev.Key() {
+	case tcell.KeyUp:
+		e.Move(-1)
+	case tcell.KeyDown:
+		e.Move(1)
+	case tcell.KeyPgUp:
+		e.Move(-uchess.EditorRows)
+	case tcell.KeyPgDn:
+		e.Move(uchess.EditorRows)
+	case tcell.KeyLeft:
+		e.Step(-1)
+	case tcell.KeyRight:
+		e.Step(1)
+	case tcell.KeyEnter:
+		e.Activate()
+	case tcell.KeyEscape:
+		gs.Editor = nil
+		ui.S.Clear()
+	case tcell.KeyRune:
+		switch ev.Rune() {
+		case ' ':
+			e.Activate()
+		case 's':
+			e.Save(gs.Config)
+		case 'q':
+			gs.Editor = nil
+			ui.S.Clear()
+		}
+	}
+}
+
+// fitText pads or cuts text to n columns
+func fitText(text string, n int) string {
+	r := []rune(text)
+	if len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return text + strings.Repeat(" ", n-len(r))
+}
+
+// drawEditor draws the options editor over the board and moves
+func drawEditor(s tcell.Screen, e *uchess.OptionsEditor, t uchess.Theme) {
+	x, y := leftMargin, topMargin-3
+	inner := editorWidth - 2
+	boxStyle := tcell.StyleDefault.Foreground(t.MoveBox)
+	textStyle := tcell.StyleDefault.Foreground(t.Msg)
+	selStyle := textStyle.Reverse(true)
+
+	title := fmt.Sprintf(" %v options (%v) ", e.Cfg.Name, e.Side)
+	drawText(s, x, y, boxStyle, "┏"+strings.Repeat("━", inner)+"┓")
+	drawText(s, x+2, y, textStyle, title)
+	header := fmt.Sprintf(" %v %v %v ", fitText("Option", 22), fitText("Value", 16), fitText("Range", 16))
+	row := func(i int, style tcell.Style, text string) {
+		drawText(s, x, y+i, boxStyle, "┃")
+		drawText(s, x+1, y+i, style, fitText(text, inner))
+		drawText(s, x+editorWidth-1, y+i, boxStyle, "┃")
+	}
+	row(1, textStyle, header)
+
+	for i := 0; i < uchess.EditorRows; i++ {
+		idx := e.Offset + i
+		if idx >= len(e.Options) {
+			row(i+2, textStyle, "")
+			continue
+		}
+		o := e.Options[idx]
+		value := e.Values[o.Name]
+		if idx == e.Selected && e.Editing {
+			value = e.Buffer + "_"
+		}
+		rng := o.Range()
+		if rng == "" {
+			rng = o.Type
+		}
+		style := textStyle
+		if idx == e.Selected {
+			style = selStyle
+		}
+		row(i+2, style, fmt.Sprintf(" %v %v %v ", fitText(o.Name, 22), fitText(value, 16), fitText(rng, 16)))
+	}
+
+	row(uchess.EditorRows+2, textStyle, " "+e.Status)
+	row(uchess.EditorRows+3, textStyle, " ↑↓ select  ←→ change  enter edit  s save  esc close")
+	drawText(s, x, y+uchess.EditorRows+4, boxStyle, "┗"+strings.Repeat("━", inner)+"┛")
+	s.HideCursor()
+}
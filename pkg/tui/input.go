@@ -1,12 +1,13 @@
-package uchess
+package tui
 
 import (
 	"strings"
 	"unicode/utf8"
 )
 
-// MaxLength defines the MaxWidth of the input buffer
-const MaxLength = 10
+// MaxLength defines the MaxWidth of the input buffer, which leaves room
+// for a FEN following the newgame command
+const MaxLength = 100
 
 // Input stores the input buffer
 type Input struct {
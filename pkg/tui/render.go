@@ -1,4 +1,4 @@
-package uchess
+package tui
 
 import (
 	"fmt"
@@ -7,6 +7,7 @@ import (
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/notnil/chess"
+	uchess "github.com/tmountain/uchess/pkg"
 )
 
 const (
@@ -33,7 +34,7 @@ func drawRune(s tcell.Screen, x, y int, style tcell.Style, r rune) {
 var DefStyle = tcell.StyleDefault.Background(tcell.ColorReset).Foreground(tcell.ColorReset)
 
 // stylePiece applies the theme's style to a piece based upon its color
-func stylePiece(p chess.Piece, sqBg tcell.Color, t Theme) tcell.Style {
+func stylePiece(p chess.Piece, sqBg tcell.Color, t uchess.Theme) tcell.Style {
 	pieceStyle := tcell.StyleDefault.Background(sqBg)
 
 	if p.Color() == chess.White {
@@ -44,7 +45,7 @@ func stylePiece(p chess.Piece, sqBg tcell.Color, t Theme) tcell.Style {
 }
 
 // squareBg returns the theme's color corresponding to the square
-func squareBg(sq chess.Square, t Theme) tcell.Color {
+func squareBg(sq chess.Square, t uchess.Theme) tcell.Color {
 	// The color is used to draw the square
 	squareColor := squareColor(sq)
 	if squareColor == chess.Black {
@@ -54,7 +55,7 @@ func squareBg(sq chess.Square, t Theme) tcell.Color {
 }
 
 // drawSquare draws a board square and its corresponding piece
-func drawSquare(s tcell.Screen, col, row int, p chess.Piece, sqBg tcell.Color, t Theme) {
+func drawSquare(s tcell.Screen, col, row int, p chess.Piece, sqBg tcell.Color, t uchess.Theme) {
 	// Empty square
 	if p == chess.NoPiece {
 		// Fill two columns wide to make it square
@@ -71,7 +72,7 @@ func drawSquare(s tcell.Screen, col, row int, p chess.Piece, sqBg tcell.Color, t
 }
 
 // drawRank draws the rank (row indicator)
-func drawRank(s tcell.Screen, col, row int, r chess.Rank, t Theme) {
+func drawRank(s tcell.Screen, col, row int, r chess.Rank, t uchess.Theme) {
 	// drawRune wants a rune
 	rank, _ := utf8.DecodeRuneInString(r.String())
 	// Display the rank (row)
@@ -80,7 +81,7 @@ func drawRank(s tcell.Screen, col, row int, r chess.Rank, t Theme) {
 }
 
 // drawMoveLabel displays the current move above the board
-func drawMoveLabel(s tcell.Screen, game *chess.Game, t Theme) {
+func drawMoveLabel(s tcell.Screen, game *chess.Game, t uchess.Theme) {
 	var nextPlayer string
 	playerTurn := game.Position().Turn()
 
@@ -93,25 +94,25 @@ func drawMoveLabel(s tcell.Screen, game *chess.Game, t Theme) {
 	drawText(s, leftMargin+2, topMargin-2, labelStyle, nextPlayer)
 }
 
-// DrawMsgLabel displays the current message from the command
-func DrawMsgLabel(s tcell.Screen, msg string, t Theme) {
+// drawMsgLabel displays the current message from the command
+func drawMsgLabel(s tcell.Screen, msg string, t uchess.Theme) {
 	topMargin := topMargin + 10
 	labelStyle := tcell.StyleDefault.Foreground(t.Msg)
 	drawText(s, leftMargin, topMargin, labelStyle, msg)
 }
 
 // drawPlayers displays the names of the players and their scores
-func drawPlayers(s tcell.Screen, config Config, game *chess.Game, t Theme) {
+func drawPlayers(s tcell.Screen, config uchess.Config, game *chess.Game, t uchess.Theme) {
 	leftMargin := leftMargin + 22
 	emojiStyle := tcell.StyleDefault.Foreground(t.Emoji)
-	black := fmt.Sprintf("%v %v", EmojiForPlayer(config.BlackPiece), config.BlackName)
+	black := fmt.Sprintf("%v %v", uchess.EmojiForPlayer(config.BlackPiece), config.BlackName)
 	drawText(s, leftMargin, topMargin-2, emojiStyle, black)
-	white := fmt.Sprintf("%v %v", EmojiForPlayer(config.WhitePiece), config.WhiteName)
+	white := fmt.Sprintf("%v %v", uchess.EmojiForPlayer(config.WhitePiece), config.WhiteName)
 	drawText(s, leftMargin, topMargin+8, emojiStyle, white)
 	fen := game.Position().String()
 	pos := strings.Split(fen, " ")
-	white, black = Advantages(pos[0])
-	whiteScore, blackScore := ScoreStr(pos[0])
+	white, black = uchess.Advantages(pos[0])
+	whiteScore, blackScore := uchess.ScoreStr(pos[0])
 	blackRes := fmt.Sprintf("%v %-10v", black, blackScore)
 	advStyle := tcell.StyleDefault.Foreground(t.Advantage)
 	drawText(s, leftMargin, topMargin-1, advStyle, blackRes)
@@ -120,10 +121,10 @@ func drawPlayers(s tcell.Screen, config Config, game *chess.Game, t Theme) {
 }
 
 // drawScore displays the current game score
-func drawScore(s tcell.Screen, cp int, game *chess.Game, t Theme) {
+func drawScore(s tcell.Screen, cp int, game *chess.Game, t uchess.Theme) {
 	topMargin := topMargin + 13
 	leftMargin := leftMargin
-	prob := WinProb(cp) * 100
+	prob := uchess.WinProb(cp) * 100
 	scoreStyle := tcell.StyleDefault.Foreground(t.Score)
 	score := fmt.Sprintf("cp=%v, pct=%-10.2f", cp, prob)
 	status := ""
@@ -139,27 +140,49 @@ func drawScore(s tcell.Screen, cp int, game *chess.Game, t Theme) {
 	drawText(s, leftMargin, topMargin+1, scoreStyle, status)
 }
 
-// Render draws the screen
-func Render(gs *GameState) {
-	drawMoveLabel(gs.S, gs.Game, gs.Theme)
-	drawBoard(gs.S, gs.Game, gs.Theme, gs.CheckWhite, gs.CheckBlack, gs.Hint)
-	drawPrompt(gs.S, gs.Input, gs.Theme)
-	drawScore(gs.S, gs.Score, gs.Game, gs.Theme)
-	drawScoreMeter(gs.S, gs.Score, gs.Theme)
-	drawPlayers(gs.S, gs.Config, gs.Game, gs.Theme)
-	drawMoves(gs.S, gs.Game, gs.Theme)
+// render draws the game state on the screen
+func render(s tcell.Screen, gs *uchess.GameState, t uchess.Theme, i *Input) {
+	drawMoveLabel(s, gs.Game, t)
+	drawBoard(s, gs.Game, t, gs.CheckWhite, gs.CheckBlack, gs.Hint, uchess.BoardAnnotations(gs))
+	drawPrompt(s, i, t)
+	drawScore(s, gs.Score, gs.Game, t)
+	drawScoreMeter(s, gs.Score, t)
+	drawPlayers(s, gs.Config, gs.Game, t)
+	if gs.Puzzle != nil {
+		drawPuzzle(s, gs.Puzzle, t)
+	} else {
+		drawOpening(s, gs.Game, t)
+	}
+	drawMoves(s, gs.Game, gs.BookPlies, t)
+	drawPonder(s, gs, t)
+	// The options editor is drawn over the board
+	if gs.Editor != nil {
+		drawEditor(s, gs.Editor, t)
+	}
 	// Update screen
-	gs.S.Show()
+	s.Show()
+}
+
+// drawPonder shows the reply the CPU opponent ponders on in the bottom
+// border of the move list
+func drawPonder(s tcell.Screen, gs *uchess.GameState, t uchess.Theme) {
+	reply := uchess.PonderMove(gs)
+	if !gs.Config.ShowPonder || reply == nil {
+		return
+	}
+	san := chess.AlgebraicNotation{}.Encode(gs.Game.Position(), reply)
+	boxStyle := tcell.StyleDefault.Foreground(t.MoveBox)
+	drawText(s, leftMargin+24, topMargin+6, boxStyle, " ponder "+san+" ")
 }
 
 // drawScoreCell draws a cell of the score meter
-func drawScoreCell(s tcell.Screen, cp, idx int, t Theme) {
+func drawScoreCell(s tcell.Screen, cp, idx int, t uchess.Theme) {
 	block := '█'
 	// At 8 start at top margin moving down as idx decreases
 	ypos := topMargin - idx + 8
 	// Round this by 5 because the meter is low resolution and we
 	// don't want values like 49.25 showing lower than 50%
-	winProb := RoundNearest(WinProb(cp)*100, 5.0)
+	winProb := uchess.RoundNearest(uchess.WinProb(cp)*100, 5.0)
 	baseColor := t.MeterBase
 	neutralColor := t.MeterNeutral
 	winColor := t.MeterWin
@@ -176,7 +199,7 @@ func drawScoreCell(s tcell.Screen, cp, idx int, t Theme) {
 
 	midStyle := tcell.StyleDefault.Foreground(t.MeterMid)
 	drawRune(s, leftMargin+19, topMargin+3, midStyle, '_')
-	if AtScale(idx, 8, winProb) {
+	if uchess.AtScale(idx, 8, winProb) {
 		drawRune(s, leftMargin+20, ypos, blockStyle, block)
 	} else {
 		baseStyle := tcell.StyleDefault.Foreground(baseColor)
@@ -185,14 +208,14 @@ func drawScoreCell(s tcell.Screen, cp, idx int, t Theme) {
 }
 
 // drawScoreMeter displays a graphical representation of the score
-func drawScoreMeter(s tcell.Screen, cp int, t Theme) {
+func drawScoreMeter(s tcell.Screen, cp int, t uchess.Theme) {
 	for i := 8; i > 0; i-- {
 		drawScoreCell(s, cp, i, t)
 	}
 }
 
 // drawPrompt draws the prompt
-func drawPrompt(s tcell.Screen, i *Input, t Theme) {
+func drawPrompt(s tcell.Screen, i *Input, t uchess.Theme) {
 	topMargin := topMargin + 11
 	promptStyle := tcell.StyleDefault.Foreground(t.Prompt)
 	drawRune(s, leftMargin, topMargin, promptStyle, '❯')
@@ -219,17 +242,37 @@ func idxToSquare(rIdx chess.Rank, fIdx int) string {
 	return fmt.Sprintf("%v%v", idxToFile(fIdx), idxToRank(rIdx))
 }
 
-// drawMoves displays recent moves
-func drawMoves(s tcell.Screen, game *chess.Game, t Theme) {
+// drawOpening displays the ECO code and name of the opening above the moves
+func drawOpening(s tcell.Screen, game *chess.Game, t uchess.Theme) {
+	leftMargin := leftMargin + 22
+	name := ""
+	if o, ok := uchess.FindOpening(game); ok {
+		name = o.String()
+	}
+	style := tcell.StyleDefault.Foreground(t.Msg)
+	drawText(s, leftMargin, topMargin-3, style, fmt.Sprintf("%-80v", name))
+}
+
+// drawPuzzle displays the puzzle number, rating and streak above the moves
+func drawPuzzle(s tcell.Screen, ps *uchess.PuzzleState, t uchess.Theme) {
+	leftMargin := leftMargin + 22
+	style := tcell.StyleDefault.Foreground(t.Msg)
+	drawText(s, leftMargin, topMargin-3, style, fmt.Sprintf("%-80v", ps))
+}
+
+// drawMoves displays recent moves, moves played from the opening book
+// are marked as such. A column fits the longest SAN, i.e., exd8=Q+,
+// followed by the book marker
+func drawMoves(s tcell.Screen, game *chess.Game, book map[int]bool, t uchess.Theme) {
 	leftMargin := leftMargin + 22
 	boxStyle := tcell.StyleDefault.Foreground(t.MoveBox)
-	drawText(s, leftMargin, topMargin, boxStyle, "┏━━━━━━━━━━━━━━━━━━━━━┓")
+	drawText(s, leftMargin, topMargin, boxStyle, "┏━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━┓")
 	for i := 0; i < 5; i++ {
-		idx, white, black := moveIdx(game, i)
-		row := fmt.Sprintf("┃ %-3v %-7v %-7v ┃", idx, white, black)
+		idx, white, black := moveIdx(game, book, i)
+		row := fmt.Sprintf("┃ %-3v %-12v %-12v ┃", idx, white, black)
 		drawText(s, leftMargin, topMargin+i+1, boxStyle, row)
 	}
-	drawText(s, leftMargin, topMargin+6, boxStyle, "┗━━━━━━━━━━━━━━━━━━━━━┛")
+	drawText(s, leftMargin, topMargin+6, boxStyle, "┗━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━┛")
 }
 
 // gameMove is used to store intermediate data in moveIdx
@@ -241,7 +284,7 @@ type gameMove = struct {
 
 // moveIdx gets the move at the requested index windowed by the
 // number of moves capable of being displayed at once (5 at this moment)
-func moveIdx(game *chess.Game, idx int) (string, string, string) {
+func moveIdx(game *chess.Game, book map[int]bool, idx int) (string, string, string) {
 	positions := game.Positions()
 	gameMoves := make([]gameMove, 0)
 	var gm gameMove
@@ -250,6 +293,9 @@ func moveIdx(game *chess.Game, idx int) (string, string, string) {
 	for i, move := range game.Moves() {
 		pos := positions[i]
 		txt := chess.AlgebraicNotation.Encode(chess.AlgebraicNotation{}, pos, move)
+		if book[i+1] {
+			txt += " book"
+		}
 		// On even indicies, write the white move / index
 		if i%2 == 0 {
 			gm = gameMove{}
@@ -261,6 +307,10 @@ func moveIdx(game *chess.Game, idx int) (string, string, string) {
 			gameMoves = append(gameMoves, gm)
 		}
 	}
+	// White's move is shown before Black replies
+	if len(game.Moves())%2 == 1 {
+		gameMoves = append(gameMoves, gm)
+	}
 
 	// We can only display five move pairs, so the moveOffset
 	// is used to paginate to the most recent
@@ -300,8 +350,138 @@ func hintSq(move *chess.Move, sq string) bool {
 	return false
 }
 
+// squareCell returns the screen coordinates of the first cell of a square
+func squareCell(sq chess.Square) (int, int) {
+	col := leftMargin + 2 + int(sq.File())*2
+	row := topMargin + 7 - int(sq.Rank())
+	return col, row
+}
+
+// arrowCell is a single glyph of an arrow drawn over a square. Arrows are
+// drawn in the spare cell to the right of a piece, and horizontal segments
+// also fill the piece cell when the square is empty
+type arrowCell struct {
+	sq    chess.Square
+	glyph rune
+	fill  bool
+}
+
+// sign returns -1, 0 or 1 matching the sign of n
+func sign(n int) int {
+	if n < 0 {
+		return -1
+	} else if n > 0 {
+		return 1
+	}
+	return 0
+}
+
+// lineGlyph returns the line drawing character for a step in the given direction
+func lineGlyph(df, dr int) rune {
+	switch {
+	case dr == 0:
+		return '─'
+	case df == 0:
+		return '│'
+	case df == dr:
+		return '╱'
+	}
+	return '╲'
+}
+
+// headGlyph returns the arrowhead for a step in the given direction
+func headGlyph(df, dr int) rune {
+	heads := map[[2]int]rune{
+		{0, 1}: '↑', {0, -1}: '↓', {1, 0}: '→', {-1, 0}: '←',
+		{1, 1}: '↗', {-1, 1}: '↖', {1, -1}: '↘', {-1, -1}: '↙',
+	}
+	return heads[[2]int{df, dr}]
+}
+
+// cornerGlyph returns the corner joining a vertical and a horizontal leg
+// where up and right describe the sides of the square being connected
+func cornerGlyph(up, right bool) rune {
+	switch {
+	case up && right:
+		return '└'
+	case up:
+		return '┘'
+	case right:
+		return '┌'
+	}
+	return '┐'
+}
+
+// arrowPath computes the glyphs needed to draw an arrow between two squares.
+// Straight and diagonal arrows follow the line between the squares, while
+// anything else (i.e., knight moves) bends once along the longer leg first
+func arrowPath(from, to chess.Square) []arrowCell {
+	f1, r1 := int(from.File()), int(from.Rank())
+	f2, r2 := int(to.File()), int(to.Rank())
+	df, dr := f2-f1, r2-r1
+	path := []arrowCell{{from, '·', false}}
+	step := func(f, r, sf, sr, n int) (int, int) {
+		for i := 1; i < n; i++ {
+			f, r = f+sf, r+sr
+			path = append(path, arrowCell{getSquare(chess.File(f), chess.Rank(r)), lineGlyph(sf, sr), sr == 0})
+		}
+		return f + sf, r + sr
+	}
+
+	if df == 0 || dr == 0 || df == dr || df == -dr {
+		n := abs(df)
+		if n == 0 {
+			n = abs(dr)
+		}
+		step(f1, r1, sign(df), sign(dr), n)
+		return append(path, arrowCell{to, headGlyph(sign(df), sign(dr)), false})
+	}
+
+	if abs(dr) > abs(df) {
+		f, r := step(f1, r1, 0, sign(dr), abs(dr))
+		corner := getSquare(chess.File(f), chess.Rank(r))
+		path = append(path, arrowCell{corner, cornerGlyph(dr < 0, df > 0), false})
+		step(f, r, sign(df), 0, abs(df))
+		return append(path, arrowCell{to, headGlyph(sign(df), 0), false})
+	}
+
+	f, r := step(f1, r1, sign(df), 0, abs(df))
+	corner := getSquare(chess.File(f), chess.Rank(r))
+	path = append(path, arrowCell{corner, cornerGlyph(dr > 0, df < 0), false})
+	step(f, r, 0, sign(dr), abs(dr))
+	return append(path, arrowCell{to, headGlyph(0, sign(dr)), false})
+}
+
+// abs returns the absolute value of n
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// drawArrows draws the arrow annotations over the board
+func drawArrows(s tcell.Screen, notes []uchess.Annotation, t uchess.Theme) {
+	for _, n := range notes {
+		if !n.IsArrow() {
+			continue
+		}
+		bg := markBg(n.Color, t)
+		for _, cell := range arrowPath(n.From, n.To) {
+			col, row := squareCell(cell.sq)
+			_, _, style, _ := s.GetContent(col+1, row)
+			style = style.Background(bg).Foreground(t.Black)
+			drawRune(s, col+1, row, style, cell.glyph)
+			// Only fill the piece cell on empty squares
+			if mainc, _, _, _ := s.GetContent(col, row); cell.fill && mainc == ' ' {
+				drawRune(s, col, row, style, cell.glyph)
+			}
+		}
+	}
+}
+
 // drawBoard draws the board on the screen
-func drawBoard(s tcell.Screen, game *chess.Game, t Theme, checkWhite, checkBlack bool, hint *chess.Move) {
+func drawBoard(s tcell.Screen, game *chess.Game, t uchess.Theme, checkWhite, checkBlack bool, hint *chess.Move, notes []uchess.Annotation) {
 	pos := game.Position()
 	board := pos.Board()
 	row := topMargin
@@ -334,6 +514,11 @@ func drawBoard(s tcell.Screen, game *chess.Game, t Theme, checkWhite, checkBlack
 				sqBg = t.SquareHint
 			}
 
+			// Show square marks
+			if color, ok := markSq(notes, sq); ok {
+				sqBg = markBg(color, t)
+			}
+
 			if (p == chess.BlackKing && checkBlack) ||
 				(p == chess.WhiteKing && checkWhite) {
 				sqBg = t.SquareCheck
@@ -347,7 +532,51 @@ func drawBoard(s tcell.Screen, game *chess.Game, t Theme, checkWhite, checkBlack
 		// Go to the next row
 		row++
 	}
+	drawArrows(s, notes, t)
 	// Display the file (column)
 	fileStyle := tcell.StyleDefault.Foreground(t.File)
 	drawText(s, leftMargin+2, row, fileStyle, "a b c d e f g h")
 }
+
+// getSquare returns a chess square given a file and a rank
+func getSquare(f chess.File, r chess.Rank) chess.Square {
+	return chess.Square((int(r) * 8) + int(f))
+}
+
+// squareColor calculates the color of the square
+func squareColor(sq chess.Square) chess.Color {
+	if ((sq / 8) % 2) == (sq % 2) {
+		return chess.Black
+	}
+	return chess.White
+}
+
+// markBg returns the theme color for an annotation color
+func markBg(color string, t uchess.Theme) tcell.Color {
+	var c, fallback tcell.Color
+	switch color {
+	case uchess.MarkRed:
+		c, fallback = t.MarkRed, uchess.ThemeBasic.MarkRed
+	case uchess.MarkBlue:
+		c, fallback = t.MarkBlue, uchess.ThemeBasic.MarkBlue
+	case uchess.MarkYellow:
+		c, fallback = t.MarkYellow, uchess.ThemeBasic.MarkYellow
+	default:
+		c, fallback = t.MarkGreen, uchess.ThemeBasic.MarkGreen
+	}
+	// Themes written before marks existed leave these unset
+	if c == tcell.ColorDefault {
+		return fallback
+	}
+	return c
+}
+
+// markSq returns the color of the square mark on sq, if any
+func markSq(notes []uchess.Annotation, sq chess.Square) (string, bool) {
+	for _, n := range notes {
+		if !n.IsArrow() && n.From == sq {
+			return n.Color, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,205 @@
+package tui
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/notnil/chess"
+	uchess "github.com/tmountain/uchess/pkg"
+)
+
+// update rewrites the golden files with the current rendering
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+const (
+	// screenWidth fits the padded opening and status lines
+	screenWidth = 110
+	// screenHeight fits everything below the board
+	screenHeight = 20
+)
+
+// renderState returns a human vs CPU game after the moves with the check
+// flags set the way the controller sets them
+func renderState(t *testing.T, moves ...string) *uchess.GameState {
+	t.Helper()
+	config := uchess.Config{WhitePiece: "human", BlackPiece: "cpu", WhiteName: "Human", BlackName: "Stockfish"}
+	gs := &uchess.GameState{Game: chess.NewGame(), Config: config}
+	// The CPU engine is never started while rendering
+	cfg := &uchess.UCIEngine{Name: "stockfish"}
+	gs.UCI.CfgWhite, gs.UCI.CfgBlack, gs.UCI.CfgHint = cfg, cfg, cfg
+	for _, m := range moves {
+		if err := gs.Game.MoveStr(m); err != nil {
+			t.Fatalf("move %v: %v", m, err)
+		}
+	}
+	gs.CheckWhite, gs.CheckBlack = uchess.InCheck(gs.Game)
+	return gs
+}
+
+// uciMoves decodes moves in UCI notation played from the current position
+func uciMoves(t *testing.T, game *chess.Game, moves ...string) []*chess.Move {
+	t.Helper()
+	pos := game.Position()
+	decoded := make([]*chess.Move, 0)
+	for _, m := range moves {
+		move, err := (chess.UCINotation{}).Decode(pos, m)
+		if err != nil {
+			t.Fatalf("move %v: %v", m, err)
+		}
+		decoded = append(decoded, move)
+		pos = pos.Update(move)
+	}
+	return decoded
+}
+
+// colorName names a color in the golden files
+func colorName(c tcell.Color) string {
+	switch c {
+	case tcell.ColorDefault:
+		return "default"
+	case tcell.ColorReset:
+		return "reset"
+	}
+	return fmt.Sprintf("#%06x", c.Hex())
+}
+
+// dumpScreen writes the runes of the screen followed by a map of the cell
+// styles, each style is given a letter in the order it first appears
+func dumpScreen(s tcell.SimulationScreen) string {
+	cells, width, height := s.GetContents()
+	var runes, styles strings.Builder
+	letters := make(map[tcell.Style]rune)
+	legend := make([]string, 0)
+	for y := 0; y < height; y++ {
+		line := make([]rune, width)
+		styleLine := make([]rune, width)
+		for x := 0; x < width; x++ {
+			cell := cells[y*width+x]
+			line[x] = ' '
+			if len(cell.Runes) > 0 {
+				line[x] = cell.Runes[0]
+			}
+			letter, ok := letters[cell.Style]
+			if !ok {
+				letter = rune('A' + len(letters))
+				if len(letters) >= 26 {
+					letter = rune('a' + len(letters) - 26)
+				}
+				letters[cell.Style] = letter
+				fg, bg, attr := cell.Style.Decompose()
+				legend = append(legend, fmt.Sprintf("%c fg=%v bg=%v attr=%v", letter, colorName(fg), colorName(bg), attr))
+			}
+			styleLine[x] = letter
+		}
+		runes.WriteString(strings.TrimRight(string(line), " ") + "\n")
+		styles.WriteString(string(styleLine) + "\n")
+	}
+	x, y, visible := s.GetCursor()
+	return fmt.Sprintf("%v\ncursor %v,%v visible=%v\n\n%v\n%v", runes.String(), x, y, visible,
+		strings.Join(legend, "\n"), styles.String())
+}
+
+// renderDump draws the game state and prompt on a simulation screen and
+// returns its dump
+func renderDump(t *testing.T, gs *uchess.GameState, prompt string) string {
+	t.Helper()
+	theme, err := uchess.ImportThemes("basic", uchess.ReadThemes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := NewInput()
+	for _, c := range prompt {
+		input.Append(c)
+	}
+	s := tcell.NewSimulationScreen("")
+	if err := s.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Fini()
+	s.SetSize(screenWidth, screenHeight)
+	s.SetStyle(DefStyle)
+	s.Clear()
+	render(s, gs, theme, input)
+	return dumpScreen(s)
+}
+
+func TestRender(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		prompt string
+		state  func(t *testing.T) *uchess.GameState
+	}{
+		{"start", "", func(t *testing.T) *uchess.GameState {
+			return renderState(t)
+		}},
+		{"check", "", func(t *testing.T) *uchess.GameState {
+			gs := renderState(t, "e4", "f5", "Qh5+")
+			gs.Score = 450
+			return gs
+		}},
+		{"hint", "", func(t *testing.T) *uchess.GameState {
+			gs := renderState(t, "e4", "e5")
+			gs.BookPlies = map[int]bool{1: true, 2: true}
+			gs.HintPV = uciMoves(t, gs.Game, "g1f3", "b8c6", "f1b5")
+			gs.Hint = gs.HintPV[0]
+			gs.Score = 35
+			return gs
+		}},
+		{"game_over", "", func(t *testing.T) *uchess.GameState {
+			gs := renderState(t, "f3", "e5", "g4", "Qh4#")
+			gs.Score = -10000
+			return gs
+		}},
+		{"long_moves", "Bb7", func(t *testing.T) *uchess.GameState {
+			gs := renderState(t, "e4", "d5", "exd5", "c6", "dxc6", "Qd6", "cxb7", "Nf6", "Nf3", "e6", "Nc3", "Be7",
+				"d4", "Nbd7", "Bd3", "h6", "O-O", "g5", "Re1", "g4", "Ne5", "h5", "bxc8=Q+")
+			// The longest SAN still fits with the book marker
+			gs.BookPlies = map[int]bool{21: true, 22: true, 23: true}
+			gs.Score = 40
+			return gs
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := renderDump(t, tc.state(t), tc.prompt)
+			golden := filepath.Join("testdata", "render", tc.name+".golden")
+			if *update {
+				if err := ioutil.WriteFile(golden, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run go test -update to create it)", err)
+			}
+			if !bytes.Equal(want, []byte(got)) {
+				t.Errorf("rendering differs from %v (run go test -update to accept it)\n%v", golden, diffLines(string(want), got))
+			}
+		})
+	}
+}
+
+// diffLines lists the lines which differ between two dumps
+func diffLines(want, got string) string {
+	w, g := strings.Split(want, "\n"), strings.Split(got, "\n")
+	var diff strings.Builder
+	for i := 0; i < len(w) || i < len(g); i++ {
+		var wl, gl string
+		if i < len(w) {
+			wl = w[i]
+		}
+		if i < len(g) {
+			gl = g[i]
+		}
+		if wl != gl {
+			fmt.Fprintf(&diff, "line %v:\n-%v\n+%v\n", i+1, wl, gl)
+		}
+	}
+	return diff.String()
+}
@@ -0,0 +1,128 @@
+// Package tui is the terminal frontend of uchess. It draws the game of a
+// controller, turns key presses into commands and shows the events the
+// controller reports
+package tui
+
+import (
+	"github.com/gdamore/tcell/v2"
+	uchess "github.com/tmountain/uchess/pkg"
+)
+
+// UI draws a game on a terminal screen
+type UI struct {
+	S     tcell.Screen       // Screen
+	Ctrl  *uchess.Controller // Controller running the game
+	Theme uchess.Theme       // Theme
+	Input *Input             // Input
+}
+
+// NewScreen returns an initialized terminal screen
+func NewScreen() (tcell.Screen, error) {
+	s, err := tcell.NewScreen()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Init(); err != nil {
+		return nil, err
+	}
+	s.SetStyle(DefStyle)
+	s.Clear()
+	return s, nil
+}
+
+// New returns a UI showing the game of the controller on the screen
+func New(s tcell.Screen, ctrl *uchess.Controller, theme uchess.Theme) *UI {
+	ui := &UI{s, ctrl, theme, NewInput()}
+	ctrl.Subscribe(ui.handle)
+	return ui
+}
+
+// handle shows an event of the controller
+func (ui *UI) handle(e uchess.Event) {
+	switch e.Kind {
+	case uchess.EventMessage:
+		ui.Message(e.Msg)
+	// Searches block until they finish, so they are shown right away
+	case uchess.EventThinking, uchess.EventProgress:
+		ui.Message(e.Msg)
+		ui.Render()
+	case uchess.EventUpdate:
+		ui.Render()
+	}
+}
+
+// Message displays a message below the prompt
+func (ui *UI) Message(msg string) {
+	drawMsgLabel(ui.S, msg, ui.Theme)
+}
+
+// Render draws the screen
+func (ui *UI) Render() {
+	render(ui.S, ui.Ctrl.State, ui.Theme, ui.Input)
+}
+
+// Run starts the game and processes input until the user quits
+func (ui *UI) Run() {
+	ui.Ctrl.Start()
+	for ui.interact() {
+	}
+}
+
+// Close restores the terminal
+func (ui *UI) Close() {
+	ui.S.Fini()
+}
+
+// interact polls user input and dispatches it. It returns false when the
+// user quits
+func (ui *UI) interact() bool {
+	gs := ui.Ctrl.State
+	// False when playing cpu vs cpu
+	isInteractive := uchess.IsInteractive(gs.Config)
+
+	// Poll event
+	ev := ui.S.PollEvent()
+
+	// Process event
+	switch ev := ev.(type) {
+	case *tcell.EventKey:
+		// The options editor takes the keys while it is open
+		if gs.Editor != nil {
+			ui.editorKey(ev)
+			ui.Render()
+			return true
+		}
+		switch ev.Key() {
+		// Quit the app
+		case tcell.KeyEscape, tcell.KeyCtrlC:
+			return false
+		// Redraw
+		case tcell.KeyCtrlL:
+			ui.S.Sync()
+		case tcell.KeyEnter:
+			cmd := ""
+			if isInteractive {
+				cmd = ui.Input.Current()
+				ui.Input.Clear()
+			}
+			// CPU matches play the next move on enter
+			ui.Ctrl.Submit(cmd)
+		// Backspace
+		case tcell.KeyBackspace, tcell.KeyBackspace2:
+			if isInteractive {
+				ui.Input.Backspace()
+				ui.Render()
+			}
+		// Append input
+		default:
+			if isInteractive {
+				ui.Input.Append(ev.Rune())
+				ui.Render()
+			}
+		}
+
+	case *tcell.EventResize:
+		ui.S.Sync()
+	}
+	return true
+}
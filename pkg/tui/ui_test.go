@@ -0,0 +1,60 @@
+package tui
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/notnil/chess"
+	uchess "github.com/tmountain/uchess/pkg"
+)
+
+// TestMain keeps the journal the controller writes out of the home directory
+func TestMain(m *testing.M) {
+	home, err := ioutil.TempDir("", "uchess-test")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	os.Setenv("HOME", home)
+	os.Setenv("USERPROFILE", home)
+	code := m.Run()
+	os.RemoveAll(home)
+	os.Exit(code)
+}
+
+func TestRunKeys(t *testing.T) {
+	config := uchess.Config{WhitePiece: "human", BlackPiece: "human", WhiteName: "Human", BlackName: "Human"}
+	gs := &uchess.GameState{Game: chess.NewGame(), Config: config}
+	// Human games do not start the engine
+	cfg := &uchess.UCIEngine{Name: "none", Path: "uchess-no-engine"}
+	gs.UCI.CfgWhite, gs.UCI.CfgBlack, gs.UCI.CfgHint = cfg, cfg, cfg
+	gs.UCI.UciWhite, gs.UCI.UciBlack, gs.UCI.UciHint = uchess.InitEngines(config, cfg, cfg, cfg)
+
+	s := tcell.NewSimulationScreen("")
+	if err := s.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Fini()
+	s.SetSize(screenWidth, screenHeight)
+	ui := New(s, uchess.NewController(gs), uchess.ThemeBasic)
+	for _, r := range "e4" {
+		s.InjectKey(tcell.KeyRune, r, tcell.ModNone)
+	}
+	s.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	s.InjectKey(tcell.KeyEscape, 0, tcell.ModNone)
+	ui.Run()
+
+	if moves := gs.Game.Moves(); len(moves) != 1 || moves[0].String() != "e2e4" {
+		t.Errorf("moves %v, want e2e4", moves)
+	}
+	if ui.Input.Length() != 0 {
+		t.Errorf("prompt %q not cleared", ui.Input.Current())
+	}
+	if dump := dumpScreen(s); !strings.Contains(dump, "Black to Move") {
+		t.Errorf("screen not redrawn after the move:\n%v", dump)
+	}
+}
@@ -0,0 +1,185 @@
+package uchess
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/notnil/chess"
+	"github.com/notnil/chess/uci"
+)
+
+const (
+	// searchGrace is how long the engine may run past its move time
+	searchGrace = 10 * time.Second
+	// searchTimeout is how long a search without a move time may run
+	searchTimeout = 5 * time.Minute
+)
+
+// uciDriver runs an engine speaking UCI
+type uciDriver struct {
+	engineBase
+	ponder *ponderSearch // Search running during the opponent's turn
+}
+
+// newUCIDriver returns a UCI driver for the config
+func newUCIDriver(cfg *UCIEngine, config Config) *uciDriver {
+	e := &uciDriver{}
+	e.proto, e.cfg, e.config = e, cfg, config
+	return e
+}
+
+// handshake starts the engine process and runs the uci handshake, which
+// declares the engine's id and options
+func (e *uciDriver) handshake() error {
+	proc, err := startProcess(e.cfg)
+	if err != nil {
+		return err
+	}
+	e.proc = proc
+	e.id = make(map[string]string)
+	e.options = nil
+	e.ponder = nil
+	if err := e.proc.send("uci"); err != nil {
+		return err
+	}
+	return e.proc.readUntil(engineTimeout, func(line string) bool {
+		switch {
+		case strings.HasPrefix(line, "id "):
+			if parts := strings.SplitN(line, " ", 3); len(parts) == 3 {
+				e.id[parts[1]] = parts[2]
+			}
+		case strings.HasPrefix(line, "option "):
+			if o, err := ParseOption(line); err == nil {
+				e.options = append(e.options, o)
+			}
+		}
+		return line == "uciok"
+	})
+}
+
+// sync waits for the engine to finish processing earlier commands
+func (e *uciDriver) sync() error {
+	if err := e.proc.send("isready"); err != nil {
+		return err
+	}
+	return e.proc.readUntil(engineTimeout, func(line string) bool {
+		return line == "readyok"
+	})
+}
+
+// sendOption sends an option value to the engine
+func (e *uciDriver) sendOption(name, value string) error {
+	return e.proc.send(uci.CmdSetOption{Name: name, Value: value}.String())
+}
+
+// press presses a button option
+func (e *uciDriver) press(name string) error {
+	return e.proc.send("setoption name " + name)
+}
+
+// idle stops the ponder search, if any
+func (e *uciDriver) idle() error {
+	return e.stopPonder()
+}
+
+// newGame tells the engine that the next search is from a different game
+func (e *uciDriver) newGame() error {
+	if err := e.proc.send("ucinewgame"); err != nil {
+		return err
+	}
+	return e.sync()
+}
+
+// search runs a search on the position and waits for the best move. A
+// ponder search on the position is continued with ponderhit, any other one
+// is stopped first
+func (e *uciDriver) search(pos *chess.Position, cmdGo uci.CmdGo) (uci.SearchResults, error) {
+	if e.ponder != nil {
+		if results, hit, err := e.endPonder(pos); hit || err != nil {
+			return results, err
+		}
+	}
+	e.proc.drain()
+	e.lines = make(map[int]uci.Info)
+	if err := e.proc.send(uci.CmdPosition{Position: pos}.String()); err != nil {
+		return uci.SearchResults{}, err
+	}
+	if err := e.proc.send(cmdGo.String()); err != nil {
+		return uci.SearchResults{}, err
+	}
+	return readSearch(e.proc, searchLimit(cmdGo), e.lines)
+}
+
+// searchLimit returns how long a search with the limits of the go command
+// may run
+func searchLimit(cmdGo uci.CmdGo) time.Duration {
+	if cmdGo.MoveTime > 0 {
+		return cmdGo.MoveTime + searchGrace
+	}
+	return searchTimeout
+}
+
+// readSearch reads the output of a search until the best move. The lines
+// with a PV are kept in lines by multipv
+func readSearch(proc *engineProcess, timeout time.Duration, lines map[int]uci.Info) (uci.SearchResults, error) {
+	var results uci.SearchResults
+	var err error
+	readErr := proc.readUntil(timeout, func(line string) bool {
+		if strings.HasPrefix(line, "bestmove") {
+			results.BestMove, results.Ponder, err = parseBestMove(line)
+			return true
+		}
+		var info uci.Info
+		if !strings.HasPrefix(line, "info ") || info.UnmarshalText([]byte(line)) != nil {
+			return false
+		}
+		// Lines without a PV (i.e., currmove updates) do not replace the score
+		if len(info.PV) > 0 {
+			if info.Multipv == 0 {
+				info.Multipv = 1
+			}
+			lines[info.Multipv] = info
+			if info.Multipv == 1 {
+				results.Info = info
+			}
+		}
+		return false
+	})
+	if readErr != nil {
+		return results, readErr
+	}
+	return results, err
+}
+
+// parseBestMove reads the best move and ponder move from the bestmove line.
+// Engines without a legal move answer (none) or 0000, which is a nil move
+func parseBestMove(line string) (*chess.Move, *chess.Move, error) {
+	parts := strings.Fields(line)
+	if len(parts) < 2 {
+		return nil, nil, fmt.Errorf("engine: best move not found in %q", line)
+	}
+	if parts[1] == "(none)" || parts[1] == "0000" {
+		return nil, nil, nil
+	}
+	best, err := chess.UCINotation{}.Decode(nil, parts[1])
+	if err != nil {
+		return nil, nil, err
+	}
+	var ponder *chess.Move
+	if len(parts) >= 4 && parts[2] == "ponder" {
+		ponder, _ = chess.UCINotation{}.Decode(nil, parts[3])
+	}
+	return best, ponder, nil
+}
+
+// Search searches the position with the limits of the go command
+func (e *uciDriver) Search(pos *chess.Position, cmdGo uci.CmdGo) (uci.SearchResults, error) {
+	var results uci.SearchResults
+	err := e.supervise(func() error {
+		var err error
+		results, err = e.search(pos, cmdGo)
+		return err
+	})
+	return results, err
+}
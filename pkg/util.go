@@ -115,13 +115,28 @@ func InCheck(game *chess.Game) (bool, bool) {
 	return false, false
 }
 
+// getCapturedPieces lists the pieces of one color missing from the board.
+// Pieces beyond the starting set were promoted from pawns, which are not
+// counted as captured
 func getCapturedPieces(pieces string, p, b, n, r, q, k string) string {
-	pawns := 8 - strings.Count(pieces, p)
-	bishops := 2 - strings.Count(pieces, b)
-	knights := 2 - strings.Count(pieces, n)
-	rooks := 2 - strings.Count(pieces, r)
-	queens := 1 - strings.Count(pieces, q)
-	kings := 1 - strings.Count(pieces, k)
+	promoted := 0
+	missing := func(piece string, start int) int {
+		n := start - strings.Count(pieces, piece)
+		if n < 0 {
+			promoted -= n
+			return 0
+		}
+		return n
+	}
+	bishops := missing(b, 2)
+	knights := missing(n, 2)
+	rooks := missing(r, 2)
+	queens := missing(q, 1)
+	kings := missing(k, 1)
+	pawns := 8 - strings.Count(pieces, p) - promoted
+	if pawns < 0 {
+		pawns = 0
+	}
 	return strings.Repeat(p, pawns) +
 		strings.Repeat(b, bishops) +
 		strings.Repeat(n, knights) +
@@ -271,3 +286,35 @@ func IsFile(path string) bool {
 	mode := fi.Mode()
 	return mode.IsRegular()
 }
+
+// findMove returns the legal move in pos matching the squares and promotion
+// of m. Moves decoded from engine output lack the tags needed to apply them
+// to a position, so they are resolved against the legal moves. If m is not
+// legal, nil is returned
+func findMove(pos *chess.Position, m *chess.Move) *chess.Move {
+	for _, valid := range pos.ValidMoves() {
+		if valid.S1() == m.S1() && valid.S2() == m.S2() && valid.Promo() == m.Promo() {
+			return valid
+		}
+	}
+	// Engines in Chess960 mode castle by taking their own rook
+	return castleMove(pos, m)
+}
+
+// sign returns -1, 0 or 1 matching the sign of n
+func sign(n int) int {
+	if n < 0 {
+		return -1
+	} else if n > 0 {
+		return 1
+	}
+	return 0
+}
+
+// abs returns the absolute value of n
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,24 @@
+package uchess
+
+import "testing"
+
+func TestGetCapturedPieces(t *testing.T) {
+	for _, tc := range []struct {
+		pieces string // Pieces of one color on the board
+		want   string
+	}{
+		{"PPPPPPPPBBNNRRQK", ""},
+		{"PPPPPPBNRRK", "PPBNQ"},
+		// A promoted queen stands in for a pawn, not a captured piece
+		{"PPPPPPPBBNNRRQQK", ""},
+		{"PPPPPBNRRQQK", "PPBN"},
+		// Promoted pieces make up for captured pieces of their kind first
+		{"PPPPPPPBBNNRRQK", "P"},
+		{"PPPPPPBBNNNRRQK", "P"},
+		{"BBNNRRQQQQQQQQQK", ""},
+	} {
+		if got := getCapturedPieces(tc.pieces, "P", "B", "N", "R", "Q", "K"); got != tc.want {
+			t.Errorf("getCapturedPieces(%v) = %v, want %v", tc.pieces, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package chess
+
+import "strings"
+
+// Castling follows the Chess960 rules, which include the standard ones:
+// the king ends on the g-file or c-file and the rook next to it on the
+// f-file or d-file. Castling rights are kept in X-FEN, where K and Q
+// stand for the outermost rook on either side of the king and a file
+// letter, i.e., B or g, names the rook when another rook stands further
+// out. Castling moves are written as the king moving two squares when
+// the king starts on the e-file and the rook in the corner, and as the
+// king taking its own rook otherwise.
+
+// castleRook is a castling right, the rook a king castles with.
+type castleRook struct {
+	color Color
+	side  Side
+	sq    Square
+}
+
+// backRank returns the rank the pieces of the color start on.
+func backRank(c Color) Rank {
+	if c == Black {
+		return Rank8
+	}
+	return Rank1
+}
+
+// kingSquare returns the square of the king of the color.
+func (b *Board) kingSquare(c Color) Square {
+	if c == Black {
+		return b.blackKingSq
+	}
+	return b.whiteKingSq
+}
+
+// outerRook returns the square of the outermost rook of the color on the
+// given side of its king, or NoSquare if the king is off its back rank or
+// there is no rook on that side.
+func (b *Board) outerRook(c Color, side Side) Square {
+	king := b.kingSquare(c)
+	if king == NoSquare || king.Rank() != backRank(c) {
+		return NoSquare
+	}
+	rook := getPiece(Rook, c)
+	if side == KingSide {
+		for f := FileH; f > king.File(); f-- {
+			if sq := getSquare(f, king.Rank()); b.Piece(sq) == rook {
+				return sq
+			}
+		}
+		return NoSquare
+	}
+	for f := FileA; f < king.File(); f++ {
+		if sq := getSquare(f, king.Rank()); b.Piece(sq) == rook {
+			return sq
+		}
+	}
+	return NoSquare
+}
+
+// rooks returns the castling rights which name a rook on the board.
+func (cr CastleRights) rooks(b *Board) []castleRook {
+	rooks := []castleRook{}
+	for _, r := range string(cr) {
+		c := White
+		if r >= 'a' && r <= 'z' {
+			c = Black
+		}
+		king := b.kingSquare(c)
+		if king == NoSquare || king.Rank() != backRank(c) {
+			continue
+		}
+		switch r {
+		case 'K', 'k':
+			if sq := b.outerRook(c, KingSide); sq != NoSquare {
+				rooks = append(rooks, castleRook{c, KingSide, sq})
+			}
+		case 'Q', 'q':
+			if sq := b.outerRook(c, QueenSide); sq != NoSquare {
+				rooks = append(rooks, castleRook{c, QueenSide, sq})
+			}
+		case 'A', 'B', 'C', 'D', 'E', 'F', 'G', 'H', 'a', 'b', 'c', 'd', 'e', 'f', 'g', 'h':
+			f := File(strings.ToLower(string(r))[0] - 'a')
+			sq := getSquare(f, king.Rank())
+			if b.Piece(sq) != getPiece(Rook, c) || f == king.File() {
+				continue
+			}
+			side := QueenSide
+			if f > king.File() {
+				side = KingSide
+			}
+			rooks = append(rooks, castleRook{c, side, sq})
+		}
+	}
+	return rooks
+}
+
+// newCastleRights returns the X-FEN castling rights for the rooks.
+func newCastleRights(b *Board, rooks []castleRook) CastleRights {
+	cr := ""
+	for _, c := range []Color{White, Black} {
+		for _, side := range []Side{KingSide, QueenSide} {
+			for _, r := range rooks {
+				if r.color != c || r.side != side {
+					continue
+				}
+				char := "K"
+				if side == QueenSide {
+					char = "Q"
+				}
+				if b.outerRook(c, side) != r.sq {
+					char = strings.ToUpper(r.sq.File().String())
+				}
+				if c == Black {
+					char = strings.ToLower(char)
+				}
+				cr += char
+				break
+			}
+		}
+	}
+	if cr == "" {
+		cr = "-"
+	}
+	return CastleRights(cr)
+}
+
+// castleMoves returns the castling moves of the side to move.
+func castleMoves(pos *Position) []*Move {
+	moves := []*Move{}
+	if pos.inCheck {
+		return moves
+	}
+	for _, r := range pos.castleRights.rooks(pos.board) {
+		if r.color != pos.turn {
+			continue
+		}
+		if m := castleMove(pos, r); m != nil {
+			moves = append(moves, m)
+		}
+	}
+	return moves
+}
+
+// castleMove returns the castling move with the rook, or nil if castling
+// is not allowed. The squares between the king and rook and their target
+// squares have to be empty and the king may not cross an attacked square.
+func castleMove(pos *Position, r castleRook) *Move {
+	king := pos.board.kingSquare(r.color)
+	rank := king.Rank()
+	kingTo, rookTo, tag := getSquare(FileG, rank), getSquare(FileF, rank), KingSideCastle
+	if r.side == QueenSide {
+		kingTo, rookTo, tag = getSquare(FileC, rank), getSquare(FileD, rank), QueenSideCastle
+	}
+	others := ^pos.board.emptySqs & ^bbForSquare(king) & ^bbForSquare(r.sq)
+	if others&(bbSpan(king, kingTo)|bbSpan(r.sq, rookTo)) != 0 {
+		return nil
+	}
+	step := Square(1)
+	if kingTo < king {
+		step = -1
+	}
+	for sq := king; sq != kingTo; {
+		sq += step
+		if squaresAreAttacked(pos, sq) {
+			return nil
+		}
+	}
+	m := &Move{s1: king, s2: r.sq}
+	if king.File() == FileE && (r.sq.File() == FileH || r.sq.File() == FileA) {
+		m.s2 = kingTo
+	}
+	m.addTag(tag)
+	addTags(m, pos)
+	// The rook may have shielded the king's target square
+	if m.HasTag(inCheck) {
+		return nil
+	}
+	return m
+}
+
+// bbSpan returns the squares from s1 to s2 on the rank of s1.
+func bbSpan(s1, s2 Square) bitboard {
+	if s2 < s1 {
+		s1, s2 = s2, s1
+	}
+	var bb bitboard
+	for sq := s1; sq <= s2; sq++ {
+		bb |= bbForSquare(sq)
+	}
+	return bb
+}
+
+// castleRookSquare returns the square the rook of a castling move starts on.
+func castleRookSquare(b *Board, m *Move) Square {
+	p := b.Piece(m.s1)
+	if b.Piece(m.s2) == getPiece(Rook, p.Color()) {
+		return m.s2
+	}
+	if m.HasTag(QueenSideCastle) {
+		return getSquare(FileA, m.s1.Rank())
+	}
+	return getSquare(FileH, m.s1.Rank())
+}
+
+// castle moves the king and rook of a castling move.
+func (b *Board) castle(m *Move) {
+	c := b.Piece(m.s1).Color()
+	rookSq := castleRookSquare(b, m)
+	kingTo, rookTo := getSquare(FileG, m.s1.Rank()), getSquare(FileF, m.s1.Rank())
+	if m.HasTag(QueenSideCastle) {
+		kingTo, rookTo = getSquare(FileC, m.s1.Rank()), getSquare(FileD, m.s1.Rank())
+	}
+	king, rook := getPiece(King, c), getPiece(Rook, c)
+	b.setBBForPiece(king, b.bbForPiece(king)&^bbForSquare(m.s1)|bbForSquare(kingTo))
+	b.setBBForPiece(rook, b.bbForPiece(rook)&^bbForSquare(rookSq)|bbForSquare(rookTo))
+	b.calcConvienceBBs(nil)
+}
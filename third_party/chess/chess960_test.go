@@ -0,0 +1,128 @@
+package chess
+
+import "testing"
+
+// chess960Pos returns the position of a FEN or fails the test.
+func chess960Pos(t *testing.T, fen string) *Position {
+	t.Helper()
+	pos, err := decodeFEN(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pos
+}
+
+func TestCastleRightsXFEN(t *testing.T) {
+	for _, tc := range []struct {
+		fen  string
+		want string
+	}{
+		// Classical rights
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "KQkq"},
+		// Outermost rooks of a Chess960 start position
+		{"1r4kr/8/8/8/8/8/8/1R4KR w KQkq - 0 1", "KQkq"},
+		// Shredder-FEN file letters name the outermost rooks as KQkq
+		{"1r4kr/8/8/8/8/8/8/1R4KR w HBhb - 0 1", "KQkq"},
+		// An inner rook keeps its file letter
+		{"rr2k3/8/8/8/8/8/8/RR2K3 w Bb - 0 1", "Bb"},
+		// Rights without a rook are dropped
+		{"4k3/8/8/8/8/8/8/R3K3 w KQkq - 0 1", "Q"},
+		// Rights of a king off its back rank are dropped
+		{"r6r/4k3/8/8/8/8/8/R3K2R w KQkq - 0 1", "KQ"},
+	} {
+		if got := chess960Pos(t, tc.fen).CastleRights().String(); got != tc.want {
+			t.Errorf("%v: castle rights %v, want %v", tc.fen, got, tc.want)
+		}
+	}
+	for _, fen := range []string{
+		"r3k2r/8/8/8/8/8/8/R3K2R w KKq - 0 1",
+		"r3k2r/8/8/8/8/8/8/R3K2R w Kx - 0 1",
+	} {
+		if _, err := decodeFEN(fen); err == nil {
+			t.Errorf("decodeFEN(%v) succeeded, want invalid castle rights", fen)
+		}
+	}
+}
+
+func TestNewCastleRights(t *testing.T) {
+	b := chess960Pos(t, "rr2k2r/8/8/8/8/8/8/RR2K2R w - - 0 1").board
+	for _, tc := range []struct {
+		rooks []castleRook
+		want  CastleRights
+	}{
+		{[]castleRook{}, "-"},
+		{[]castleRook{{White, KingSide, H1}, {White, QueenSide, A1}}, "KQ"},
+		// Rights are written white first, king side first
+		{[]castleRook{{Black, QueenSide, A8}, {White, QueenSide, A1}, {Black, KingSide, H8}}, "Qkq"},
+		// A rook with another one further out is named by its file
+		{[]castleRook{{White, QueenSide, B1}, {Black, QueenSide, B8}}, "Bb"},
+	} {
+		if got := newCastleRights(b, tc.rooks); got != tc.want {
+			t.Errorf("newCastleRights(%v) = %v, want %v", tc.rooks, got, tc.want)
+		}
+	}
+}
+
+func TestCastleMove(t *testing.T) {
+	for _, tc := range []struct {
+		fen  string
+		rook castleRook
+		want string // Move in UCI notation, empty when castling is not allowed
+		tag  MoveTag
+	}{
+		// The king moves two squares from the classical squares
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", castleRook{White, KingSide, H1}, "e1g1", KingSideCastle},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", castleRook{Black, QueenSide, A8}, "e8c8", QueenSideCastle},
+		// and takes its own rook otherwise
+		{"1r4kr/8/8/8/8/8/8/1R4KR w KQkq - 0 1", castleRook{White, KingSide, H1}, "g1h1", KingSideCastle},
+		{"1r4kr/8/8/8/8/8/8/1R4KR w KQkq - 0 1", castleRook{White, QueenSide, B1}, "g1b1", QueenSideCastle},
+		{"rk5r/8/8/8/8/8/8/RK5R w KQkq - 0 1", castleRook{White, QueenSide, A1}, "b1a1", QueenSideCastle},
+		// The king may already stand on its target square
+		{"6kr/8/8/8/8/8/8/6KR w KQkq - 0 1", castleRook{White, KingSide, H1}, "g1h1", KingSideCastle},
+		// A piece between the king and rook
+		{"rk5r/8/8/8/8/8/8/RK3N1R w KQkq - 0 1", castleRook{White, KingSide, H1}, "", 0},
+		// A piece on the rook's target square
+		{"rk5r/8/8/8/8/8/8/RK1N3R w KQkq - 0 1", castleRook{White, QueenSide, A1}, "", 0},
+		// The king crosses an attacked square
+		{"1r4kr/8/8/8/8/8/3r4/1R4KR w KQkq - 0 1", castleRook{White, QueenSide, B1}, "", 0},
+		// The castling rook shields the king's target square
+		{"k7/8/8/8/8/8/8/qRK4R w KQ - 0 1", castleRook{White, QueenSide, B1}, "", 0},
+	} {
+		pos := chess960Pos(t, tc.fen)
+		m := castleMove(pos, tc.rook)
+		switch {
+		case tc.want == "" && m != nil:
+			t.Errorf("%v: castleMove(%v) = %v, want nil", tc.fen, tc.rook, m)
+		case tc.want == "":
+		case m == nil:
+			t.Errorf("%v: castleMove(%v) = nil, want %v", tc.fen, tc.rook, tc.want)
+		case m.String() != tc.want || !m.HasTag(tc.tag) || m.HasTag(Capture):
+			t.Errorf("%v: castleMove(%v) = %v, want %v tagged %v", tc.fen, tc.rook, m, tc.want, tc.tag)
+		}
+	}
+}
+
+func TestUCINotationDecodeCastle(t *testing.T) {
+	for _, tc := range []struct {
+		fen  string
+		move string
+		tag  MoveTag
+		want string // Position after castling
+	}{
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1g1", KingSideCastle, "r3k2r/8/8/8/8/8/8/R4RK1 b kq - 0 1"},
+		{"rk5r/8/8/8/8/8/8/RK5R w KQkq - 0 1", "b1a1", QueenSideCastle, "rk5r/8/8/8/8/8/8/2KR3R b kq - 0 1"},
+		{"1r4kr/8/8/8/8/8/8/1R4KR b KQkq - 0 1", "g8h8", KingSideCastle, "1r3rk1/8/8/8/8/8/8/1R4KR w KQ - 0 2"},
+	} {
+		pos := chess960Pos(t, tc.fen)
+		m, err := UCINotation{}.Decode(pos, tc.move)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !m.HasTag(tc.tag) || m.HasTag(Capture) {
+			t.Errorf("%v: Decode(%v) not tagged %v", tc.fen, tc.move, tc.tag)
+		}
+		if got := pos.Update(m).String(); got != tc.want {
+			t.Errorf("%v: after %v got %v, want %v", tc.fen, tc.move, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+#!/bin/sh
+# update.sh rebuilds this copy of notnil/chess from the pinned release and
+# chess960.patch. With -check it rebuilds the copy in a scratch directory
+# and lists any difference from this one instead.
+#
+#	./update.sh [-check]
+set -eu
+
+version=v1.5.0
+sum=h1:BcdmSGqZYhoqHsAqNpVTtPwRMOA4Sj8iZY1ZuPW4Umg=
+
+fork=$(cd "$(dirname "$0")" && pwd)
+scratch=$(mktemp -d)
+trap 'rm -rf "$scratch"' EXIT
+
+# Download the release outside of the uchess module, which replaces it
+info=$(cd "$scratch" && GOFLAGS=-mod=mod go mod download -json "github.com/notnil/chess@$version")
+upstream=$(echo "$info" | sed -n 's/^[[:space:]]*"Dir": "\(.*\)",$/\1/p')
+if ! echo "$info" | grep -q "\"Sum\": \"$sum\""; then
+	echo "update.sh: notnil/chess $version does not match $sum" >&2
+	exit 1
+fi
+
+out=$fork
+if [ "${1:-}" = "-check" ]; then
+	out=$scratch/chess
+	cp -R "$fork" "$out"
+fi
+
+# Files of the copy come from the release, except for this script, the
+# README and the files added by the patch
+cd "$out"
+added=$(sed -n '/^--- \/dev\/null$/{n;s/^+++ b\///p;}' chess960.patch)
+find . -type f | sed 's/^\.\///' | while read -r file; do
+	case "$file" in
+	update.sh | README.md | chess960.patch) continue ;;
+	esac
+	if echo "$added" | grep -qx "$file"; then
+		rm "$file"
+	else
+		cp "$upstream/$file" "$file"
+		chmod 644 "$file"
+	fi
+done
+patch -s -p1 <chess960.patch
+
+if [ "$out" != "$fork" ]; then
+	diff -r "$fork" "$out"
+fi